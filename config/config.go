@@ -1,6 +1,7 @@
 package config
 
 import (
+	"classOrder-backend/internal/schedule"
 	"log"
 	"os"
 
@@ -9,9 +10,16 @@ import (
 
 // Config 是整个项目的配置结构体
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Schedule  ScheduleConfig  `yaml:"schedule"`
+	Login     LoginConfig     `yaml:"login"`
+	Notify    NotifyConfig    `yaml:"notify"`
+	Password  PasswordConfig  `yaml:"password"`
+	TwoFactor TwoFactorConfig `yaml:"two_factor"`
+	Student   StudentConfig   `yaml:"student"`
+	Calendar  CalendarConfig  `yaml:"calendar"`
 }
 
 // ServerConfig 服务器配置
@@ -33,8 +41,61 @@ type DatabaseConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret     string `yaml:"secret"`
-	Expiration int    `yaml:"expiration"`
+	Secret             string `yaml:"secret"`
+	Expiration         int    `yaml:"expiration"`           // 旧配置，未设置 access_token_minutes 时访问令牌的有效期（小时）
+	AccessTokenMinutes int    `yaml:"access_token_minutes"` // 访问令牌有效期（分钟）
+	RefreshTokenDays   int    `yaml:"refresh_token_days"`   // 刷新令牌有效期（天）
+}
+
+// ScheduleConfig 排课配置
+type ScheduleConfig struct {
+	DefaultWorkingHours string `yaml:"default_working_hours"` // 未设置工作时间的教练使用的默认时段，如 "09:00-18:00"；为空表示不限制
+	SlotMinutes         int    `yaml:"slot_minutes"`          // 可预约时间单元的长度（分钟）
+	MaxRangeDays        int    `yaml:"max_range_days"`        // 一次查询可用时间的最大天数
+	SeasonEnd           string `yaml:"season_end"`            // 雪季结束日期 MM-DD，用于提醒本雪季内到期的教练证书
+}
+
+// LoginConfig 登录防暴力破解配置
+type LoginConfig struct {
+	FreeAttempts       int `yaml:"free_attempts"`        // 连续失败多少次后开始退避
+	MaxBackoffSeconds  int `yaml:"max_backoff_seconds"`  // 退避等待时间上限（秒），每次失败翻倍
+	LockoutThreshold   int `yaml:"lockout_threshold"`    // 同一用户名连续失败多少次后锁定
+	IPLockoutThreshold int `yaml:"ip_lockout_threshold"` // 同一IP连续失败多少次后锁定
+	LockoutMinutes     int `yaml:"lockout_minutes"`      // 锁定时长（分钟），超过该时长没有失败也会清零计数
+}
+
+// NotifyConfig 通知渠道配置
+type NotifyConfig struct {
+	Driver   string `yaml:"driver"`    // log 或 file
+	FilePath string `yaml:"file_path"` // driver 为 file 时写入的文件
+}
+
+// PasswordConfig 密码重置配置
+type PasswordConfig struct {
+	ResetTokenMinutes int    `yaml:"reset_token_minutes"` // 重置令牌有效期（分钟）
+	ResetURL          string `yaml:"reset_url"`           // 重置页面地址，令牌会作为 token 参数附加在后面
+}
+
+// TwoFactorConfig 两步验证配置
+type TwoFactorConfig struct {
+	Issuer        string   `yaml:"issuer"`         // 验证器应用中显示的名称
+	RequiredRoles []string `yaml:"required_roles"` // 必须启用两步验证的角色，例如 ["admin"]
+}
+
+// StudentConfig 学员自助注册与预约配置
+type StudentConfig struct {
+	AllowRegistration bool   `yaml:"allow_registration"` // 是否开放学员自助注册
+	BookingStatus     string `yaml:"booking_status"`     // 学员自助预约的初始状态，pending 需要前台或教练确认
+	MaxAdvanceDays    int    `yaml:"max_advance_days"`   // 最多提前多少天预约
+	CancelHours       int    `yaml:"cancel_hours"`       // 课程开始前多少小时内不能自行取消
+	MaxOpenBookings   int    `yaml:"max_open_bookings"`  // 每个学员最多同时持有多少个未开始的待确认或已确认预约
+	RegisterLimit     int    `yaml:"register_limit"`     // 同一IP连续注册多少次后暂停注册，退避和锁定时长与登录相同
+}
+
+// CalendarConfig 日历订阅配置
+type CalendarConfig struct {
+	BaseURL  string `yaml:"base_url"`  // 订阅链接的地址前缀，例如 https://booking.example.com；为空时使用请求的 Host
+	PastDays int    `yaml:"past_days"` // 订阅中包含多少天以前的课程
 }
 
 // Cfg 是一个全局可访问的配置实例
@@ -74,6 +135,13 @@ func InitConfig() {
 		log.Fatalf("Failed to parse config file: %v", err)
 	}
 
+	// 默认工作时段无效时所有未设置模板的教练都无法预约，启动时直接报错
+	if raw := config.Schedule.DefaultWorkingHours; raw != "" {
+		if _, err := schedule.ParseSlots(raw); err != nil {
+			log.Fatalf("Invalid schedule.default_working_hours %q: %v", raw, err)
+		}
+	}
+
 	Cfg = &config
 	log.Printf("Configuration loaded successfully from %s", configPath)
 } 
\ No newline at end of file
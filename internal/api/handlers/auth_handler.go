@@ -17,13 +17,16 @@ import (
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
+	Role     string `json:"role"` // 可选，提供时必须与账号的角色一致
 }
 
 // LoginResponse 定义了成功登录后返回的JSON结构
 type LoginResponse struct {
-	Token string `json:"token"`
-	Role  string `json:"role"`
+	Token              string `json:"token"`
+	RefreshToken       string `json:"refresh_token"`
+	ExpiresIn          int    `json:"expires_in"` // 访问令牌有效期（秒）
+	Role               string `json:"role"`
+	MustChangePassword bool   `json:"must_change_password,omitempty"` // 为 true 时只能先调用修改密码接口
 }
 
 // LoginHandler 处理用户登录请求
@@ -35,54 +38,60 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	log.Printf("收到登录请求: username=%s, role=%s", req.Username, req.Role)
+	// 1. 同一用户名或IP连续失败过多时，在校验密码前直接拒绝
+	wait, locked, err := loginRetryAfter(usernameSubject(req.Username), ipSubject(c.ClientIP()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if wait > 0 {
+		respondLoginThrottled(c, wait, locked)
+		return
+	}
 
-	// 1. 从数据库中查找用户
+	// 2. 从数据库中查找用户并比较密码哈希值，两种失败返回同样的错误
 	var user models.User
-	result := database.DB.Where("username = ? AND role = ?", req.Username, req.Role).First(&user)
-	if result.Error != nil {
-		log.Printf("用户查找失败: username=%s, role=%s, error=%v", req.Username, req.Role, result.Error)
+	query := database.DB.Where("username = ?", req.Username)
+	if req.Role != "" {
+		query = query.Where("role = ?", req.Role)
+	}
+	result := query.First(&user)
+	if result.Error != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		log.Printf("登录失败: ip=%s", c.ClientIP())
+		recordLoginFailures(req.Username, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	log.Printf("找到用户: id=%d, username=%s, role=%s", user.ID, user.Username, user.Role)
-
-	// 2. 比较密码哈希值
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
-	if err != nil {
-		log.Printf("密码验证失败: username=%s", req.Username)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	// 停用的账号在密码正确时才提示，避免泄露账号是否存在
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
 		return
 	}
 
-	// 3. 生成JWT
-	token, err := generateJWT(user)
-	if err != nil {
-		log.Printf("生成JWT失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+	// 3. 已启用两步验证或角色要求两步验证时，先返回临时令牌，完成第二步后再签发正式令牌
+	if user.TOTPEnabled || twoFactorRequired(user) {
+		respondSecondFactor(c, user)
 		return
 	}
 
-	log.Printf("登录成功: username=%s, role=%s", user.Username, user.Role)
-
-	c.JSON(http.StatusOK, LoginResponse{
-		Token: token,
-		Role:  user.Role,
-	})
+	// 4. 创建会话并生成访问令牌和刷新令牌
+	completeLogin(c, user, nil)
 }
 
-// generateJWT 为指定用户生成JWT令牌
-func generateJWT(user models.User) (string, error) {
+// generateJWT 为指定用户生成短期有效的访问令牌，sid 关联登录会话以便撤销
+func generateJWT(user models.User, sessionID string) (string, error) {
 	cfg := config.Cfg.JWT
 	
 	// 创建JWT的claims
 	claims := jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     user.Role,
-		"exp":      time.Now().Add(time.Hour * time.Duration(cfg.Expiration)).Unix(),
-		"iat":      time.Now().Unix(),
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"role":       user.Role,
+		"sid":        sessionID,
+		"pwd_change": user.MustChangePassword, // 为 true 时只能访问修改密码接口
+		"exp":        time.Now().Add(accessTokenTTL()).Unix(),
+		"iat":        time.Now().Unix(),
 	}
 
 	// 使用HS256签名算法创建一个新的token对象
@@ -0,0 +1,483 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var (
+	errOutsideAvailability = errors.New("outside coach availability")
+	errInBlackout          = errors.New("inside blackout period")
+)
+
+// defaultWorkingHours 返回未设置每周模板的教练使用的工作时段，未配置默认时段时全天可预约
+func defaultWorkingHours() []schedule.Slot {
+	raw := config.Cfg.Schedule.DefaultWorkingHours
+	if raw == "" {
+		return []schedule.Slot{fullDay}
+	}
+	// 配置在启动时已校验
+	slots, _ := schedule.ParseSlots(raw)
+	return slots
+}
+
+// slotMinutes 返回可预约时间单元的长度（分钟）
+func slotMinutes() int {
+	if m := config.Cfg.Schedule.SlotMinutes; m > 0 {
+		return m
+	}
+	return 30
+}
+
+// coachCalendar 缓存教练的每周工作时段、日期覆盖和休假停课，用于计算某天的可预约时段
+type coachCalendar struct {
+	weekly    map[int][]schedule.Slot
+	hasWeekly bool
+	overrides map[string][]models.CoachDateOverride
+	blackouts []models.Blackout
+}
+
+// loadCoachCalendar 加载教练在 [from, to] 范围内计算工作时段所需的数据
+func loadCoachCalendar(tx *gorm.DB, coachID uint, from, to time.Time) (*coachCalendar, error) {
+	var hours []models.CoachWorkingHour
+	if err := tx.Where("coach_id = ?", coachID).Find(&hours).Error; err != nil {
+		return nil, err
+	}
+	var overrides []models.CoachDateOverride
+	if err := tx.Where("coach_id = ? AND date BETWEEN ? AND ?", coachID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	var blackouts []models.Blackout
+	if err := tx.Where("(coach_id = ? OR coach_id IS NULL) AND start_date <= ? AND end_date >= ?", coachID, to.Format("2006-01-02"), from.Format("2006-01-02")).
+		Find(&blackouts).Error; err != nil {
+		return nil, err
+	}
+
+	cal := &coachCalendar{
+		weekly:    make(map[int][]schedule.Slot),
+		hasWeekly: len(hours) > 0,
+		overrides: make(map[string][]models.CoachDateOverride),
+		blackouts: blackouts,
+	}
+	for _, h := range hours {
+		cal.weekly[h.Weekday] = append(cal.weekly[h.Weekday], schedule.Slot{Start: h.StartTime, End: h.EndTime})
+	}
+	for _, o := range overrides {
+		key := o.Date.Format("2006-01-02")
+		cal.overrides[key] = append(cal.overrides[key], o)
+	}
+	return cal, nil
+}
+
+// workingHours 返回教练某天的工作时段：日期覆盖优先，其次为每周模板，
+// 教练未设置任何每周模板时使用配置中的默认时段，未配置时不限制
+func (cal *coachCalendar) workingHours(date time.Time) []schedule.Slot {
+	if overrides, ok := cal.overrides[date.Format("2006-01-02")]; ok {
+		var result []schedule.Slot
+		for _, o := range overrides {
+			if o.Closed {
+				return nil
+			}
+			result = append(result, schedule.Slot{Start: o.StartTime, End: o.EndTime})
+		}
+		return schedule.Merge(result)
+	}
+	if !cal.hasWeekly {
+		return defaultWorkingHours()
+	}
+	return schedule.Merge(cal.weekly[int(date.Weekday())])
+}
+
+// blackoutSlots 返回某天被休假或停课占用的时间段
+func (cal *coachCalendar) blackoutSlots(date time.Time) []schedule.Slot {
+	var result []schedule.Slot
+	for _, b := range cal.blackouts {
+		if blackoutCoversDate(b, date) {
+			result = append(result, blackoutSlot(b))
+		}
+	}
+	return schedule.Merge(result)
+}
+
+// windows 返回教练某天实际可预约的时段，即工作时段扣除休假停课
+func (cal *coachCalendar) windows(date time.Time) []schedule.Slot {
+	return schedule.Subtract(cal.workingHours(date), cal.blackoutSlots(date))
+}
+
+// checkAvailability 检查时间段是否完整落在教练当天的工作时段内，且不处于休假停课期间
+func checkAvailability(tx *gorm.DB, coachID uint, date time.Time, slots []schedule.Slot) error {
+	cal, err := loadCoachCalendar(tx, coachID, date, date)
+	if err != nil {
+		return err
+	}
+	if !schedule.Covers(cal.workingHours(date), slots) {
+		return errOutsideAvailability
+	}
+	if _, _, overlap := schedule.AnyOverlap(slots, cal.blackoutSlots(date)); overlap {
+		return errInBlackout
+	}
+	return nil
+}
+
+// coachesFreeOn 从 coachIDs 中筛选出在 date 当天还有至少一个可预约时间单元的教练
+func coachesFreeOn(coachIDs []uint, date time.Time) ([]uint, error) {
+	if len(coachIDs) == 0 {
+		return nil, nil
+	}
+	var bookings []models.Booking
+	if err := database.DB.Preload("Slots").
+		Where("coach_id IN ? AND booking_date = ? AND status <> ?", coachIDs, date.Format("2006-01-02"), models.BookingStatusCancelled).
+		Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	booked := make(map[uint][]schedule.Slot)
+	for _, b := range bookings {
+		booked[b.CoachID] = append(booked[b.CoachID], toScheduleSlots(b.Slots)...)
+	}
+	unit := slotMinutes()
+	var result []uint
+	for _, id := range coachIDs {
+		cal, err := loadCoachCalendar(database.DB, id, date, date)
+		if err != nil {
+			return nil, err
+		}
+		if len(schedule.Split(schedule.Subtract(cal.windows(date), booked[id]), unit)) > 0 {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// GetCoachAvailabilityHandler 计算教练在 from 到 to 之间每天的空闲时间
+func GetCoachAvailabilityHandler(c *gin.Context) {
+	coachID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	maxDays := config.Cfg.Schedule.MaxRangeDays
+	if maxDays <= 0 {
+		maxDays = 62
+	}
+	from, to, ok := parseDateRange(c, maxDays)
+	if !ok {
+		return
+	}
+
+	var coach models.Coach
+	if err := database.DB.First(&coach, coachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+
+	cal, err := loadCoachCalendar(database.DB, coachID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load working hours"})
+		return
+	}
+	var bookings []models.Booking
+	if err := database.DB.Preload("Slots").
+		Where("coach_id = ? AND booking_date BETWEEN ? AND ? AND status <> ?", coachID, from.Format("2006-01-02"), to.Format("2006-01-02"), models.BookingStatusCancelled).
+		Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+	booked := make(map[string][]schedule.Slot)
+	for _, b := range bookings {
+		key := b.BookingDate.Format("2006-01-02")
+		booked[key] = append(booked[key], toScheduleSlots(b.Slots)...)
+	}
+
+	unit := slotMinutes()
+	var days []gin.H
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		windows := cal.windows(d)
+		free := schedule.Subtract(windows, booked[key])
+		days = append(days, gin.H{
+			"date":          key,
+			"weekday":       int(d.Weekday()),
+			"working_hours": cal.workingHours(d),
+			"blackouts":     cal.blackoutSlots(d),
+			"booked":        schedule.Merge(booked[key]),
+			"free":          free,
+			"free_units":    schedule.Split(free, unit),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coach_id":     coachID,
+		"slot_minutes": unit,
+		"days":         days,
+	})
+}
+
+// WorkingHourInput 描述每周模板中的一个工作时段
+type WorkingHourInput struct {
+	Weekday int    `json:"weekday"` // 0 表示周日
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// SetWorkingHoursRequest 整体替换教练的每周工作时段，传空数组表示恢复默认时段
+type SetWorkingHoursRequest struct {
+	WorkingHours []WorkingHourInput `json:"working_hours"`
+}
+
+// SetDateOverrideRequest 设置某一天的工作时段覆盖，会替换该日期已有的覆盖
+type SetDateOverrideRequest struct {
+	Date   string          `json:"date" binding:"required"` // YYYY-MM-DD
+	Closed bool            `json:"closed"`
+	Slots  []schedule.Slot `json:"slots"`
+	Note   string          `json:"note"`
+}
+
+func workingHoursResponse(hours []models.CoachWorkingHour) []WorkingHourInput {
+	result := make([]WorkingHourInput, 0, len(hours))
+	for _, h := range hours {
+		result = append(result, WorkingHourInput{Weekday: h.Weekday, Start: h.StartTime, End: h.EndTime})
+	}
+	return result
+}
+
+func overrideResponse(o models.CoachDateOverride) gin.H {
+	return gin.H{
+		"id":     o.ID,
+		"date":   o.Date.Format("2006-01-02"),
+		"closed": o.Closed,
+		"start":  o.StartTime,
+		"end":    o.EndTime,
+		"note":   o.Note,
+	}
+}
+
+func respondWorkingHours(c *gin.Context, coachID uint) {
+	var hours []models.CoachWorkingHour
+	if err := database.DB.Where("coach_id = ?", coachID).Order("weekday, start_time").Find(&hours).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve working hours"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"coach_id":      coachID,
+		"uses_default":  len(hours) == 0,
+		"default_hours": defaultWorkingHours(),
+		"working_hours": workingHoursResponse(hours),
+	})
+}
+
+func replaceWorkingHours(c *gin.Context, coachID uint) {
+	var req SetWorkingHoursRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	// 按星期分组校验，同一天内的时段不能重叠
+	byWeekday := make(map[int][]schedule.Slot)
+	for _, h := range req.WorkingHours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weekday must be between 0 and 6"})
+			return
+		}
+		byWeekday[h.Weekday] = append(byWeekday[h.Weekday], schedule.Slot{Start: h.Start, End: h.End})
+	}
+	var rows []models.CoachWorkingHour
+	for weekday := 0; weekday <= 6; weekday++ {
+		if len(byWeekday[weekday]) == 0 {
+			continue
+		}
+		slots, err := schedule.Normalize(byWeekday[weekday])
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid working hours: " + err.Error()})
+			return
+		}
+		for _, s := range slots {
+			rows = append(rows, models.CoachWorkingHour{CoachID: coachID, Weekday: weekday, StartTime: s.Start, EndTime: s.End})
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coach_id = ?", coachID).Delete(&models.CoachWorkingHour{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update working hours"})
+		return
+	}
+	respondWorkingHours(c, coachID)
+}
+
+func respondOverrides(c *gin.Context, coachID uint) {
+	from, to, ok := parseDateRange(c, 0)
+	if !ok {
+		return
+	}
+	if c.Query("to") == "" {
+		to = from.AddDate(0, 0, 90)
+	}
+	var overrides []models.CoachDateOverride
+	if err := database.DB.Where("coach_id = ? AND date BETWEEN ? AND ?", coachID, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("date, start_time").Find(&overrides).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve overrides"})
+		return
+	}
+	resp := make([]gin.H, 0, len(overrides))
+	for _, o := range overrides {
+		resp = append(resp, overrideResponse(o))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func replaceOverride(c *gin.Context, coachID uint) {
+	var req SetDateOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		return
+	}
+
+	var rows []models.CoachDateOverride
+	if req.Closed {
+		rows = append(rows, models.CoachDateOverride{CoachID: coachID, Date: date, Closed: true, Note: req.Note})
+	} else {
+		slots, err := schedule.Normalize(req.Slots)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time slots: " + err.Error()})
+			return
+		}
+		for _, s := range slots {
+			rows = append(rows, models.CoachDateOverride{CoachID: coachID, Date: date, StartTime: s.Start, EndTime: s.End, Note: req.Note})
+		}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coach_id = ? AND date = ?", coachID, req.Date).Delete(&models.CoachDateOverride{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save override"})
+		return
+	}
+	resp := make([]gin.H, 0, len(rows))
+	for _, o := range rows {
+		resp = append(resp, overrideResponse(o))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func removeOverride(c *gin.Context, coachID uint) {
+	date, err := time.Parse("2006-01-02", c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		return
+	}
+	if err := database.DB.Where("coach_id = ? AND date = ?", coachID, date.Format("2006-01-02")).
+		Delete(&models.CoachDateOverride{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete override"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Override deleted successfully"})
+}
+
+// coachIDFromParam 解析路径中的教练ID并确认教练存在
+func coachIDFromParam(c *gin.Context) (uint, bool) {
+	coachID, ok := parseIDParam(c, "id")
+	if !ok {
+		return 0, false
+	}
+	var coach models.Coach
+	if err := database.DB.First(&coach, coachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return 0, false
+	}
+	return coachID, true
+}
+
+// GetCoachWorkingHoursHandler 获取教练的每周工作时段
+func GetCoachWorkingHoursHandler(c *gin.Context) {
+	if coachID, ok := coachIDFromParam(c); ok {
+		respondWorkingHours(c, coachID)
+	}
+}
+
+// SetCoachWorkingHoursHandler 管理员设置教练的每周工作时段
+func SetCoachWorkingHoursHandler(c *gin.Context) {
+	if coachID, ok := coachIDFromParam(c); ok {
+		replaceWorkingHours(c, coachID)
+	}
+}
+
+// ListCoachOverridesHandler 管理员查看教练的日期覆盖
+func ListCoachOverridesHandler(c *gin.Context) {
+	if coachID, ok := coachIDFromParam(c); ok {
+		respondOverrides(c, coachID)
+	}
+}
+
+// SetCoachOverrideHandler 管理员设置教练某天的工作时段覆盖
+func SetCoachOverrideHandler(c *gin.Context) {
+	if coachID, ok := coachIDFromParam(c); ok {
+		replaceOverride(c, coachID)
+	}
+}
+
+// DeleteCoachOverrideHandler 管理员删除教练某天的工作时段覆盖
+func DeleteCoachOverrideHandler(c *gin.Context) {
+	if coachID, ok := coachIDFromParam(c); ok {
+		removeOverride(c, coachID)
+	}
+}
+
+// GetOwnWorkingHoursHandler 教练查看自己的每周工作时段
+func GetOwnWorkingHoursHandler(c *gin.Context) {
+	if coach, ok := currentCoach(c); ok {
+		respondWorkingHours(c, coach.ID)
+	}
+}
+
+// SetOwnWorkingHoursHandler 教练设置自己的每周工作时段
+func SetOwnWorkingHoursHandler(c *gin.Context) {
+	if coach, ok := currentCoach(c); ok {
+		replaceWorkingHours(c, coach.ID)
+	}
+}
+
+// ListOwnOverridesHandler 教练查看自己的日期覆盖
+func ListOwnOverridesHandler(c *gin.Context) {
+	if coach, ok := currentCoach(c); ok {
+		respondOverrides(c, coach.ID)
+	}
+}
+
+// SetOwnOverrideHandler 教练设置自己某天的工作时段覆盖
+func SetOwnOverrideHandler(c *gin.Context) {
+	if coach, ok := currentCoach(c); ok {
+		replaceOverride(c, coach.ID)
+	}
+}
+
+// DeleteOwnOverrideHandler 教练删除自己某天的工作时段覆盖
+func DeleteOwnOverrideHandler(c *gin.Context) {
+	if coach, ok := currentCoach(c); ok {
+		removeOverride(c, coach.ID)
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// fullDay 表示整天的时间段，用于整天的休假或停课
+var fullDay = schedule.Slot{Start: "00:00", End: "23:59"}
+
+// CreateBlackoutRequest 定义了创建休假/停课的请求结构
+type CreateBlackoutRequest struct {
+	CoachID   *uint  `json:"coach_id"`                      // 为空表示全校停课，仅管理员可用
+	StartDate string `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate   string `json:"end_date"`                      // YYYY-MM-DD，为空时与 start_date 相同
+	StartTime string `json:"start_time"`                    // HH:MM，与 end_time 同时为空表示整天
+	EndTime   string `json:"end_time"`
+	Reason    string `json:"reason"`
+}
+
+// blackoutCoversDate 判断休假停课是否包含某一天
+func blackoutCoversDate(b models.Blackout, date time.Time) bool {
+	day := date.Format("2006-01-02")
+	return b.StartDate.Format("2006-01-02") <= day && day <= b.EndDate.Format("2006-01-02")
+}
+
+// blackoutSlot 返回休假停课在每一天中占用的时间段
+func blackoutSlot(b models.Blackout) schedule.Slot {
+	if b.StartTime == "" && b.EndTime == "" {
+		return fullDay
+	}
+	return schedule.Slot{Start: b.StartTime, End: b.EndTime}
+}
+
+func blackoutResponse(b models.Blackout) gin.H {
+	return gin.H{
+		"id":          b.ID,
+		"coach_id":    b.CoachID,
+		"school_wide": b.CoachID == nil,
+		"start_date":  b.StartDate.Format("2006-01-02"),
+		"end_date":    b.EndDate.Format("2006-01-02"),
+		"start_time":  b.StartTime,
+		"end_time":    b.EndTime,
+		"reason":      b.Reason,
+		"created_by":  b.CreatedBy,
+		"created_at":  b.CreatedAt,
+	}
+}
+
+// findAffectedBookings 查找与休假停课时间重叠的待确认或已确认预约
+func findAffectedBookings(tx *gorm.DB, b models.Blackout) ([]models.Booking, error) {
+	var bookings []models.Booking
+	query := tx.Preload("Slots").Preload("Course").
+		Where("booking_date BETWEEN ? AND ?", b.StartDate.Format("2006-01-02"), b.EndDate.Format("2006-01-02")).
+		Where("status IN ?", []string{models.BookingStatusPending, models.BookingStatusConfirmed})
+	if b.CoachID != nil {
+		query = query.Where("coach_id = ?", *b.CoachID)
+	}
+	if err := query.Order("booking_date, coach_id").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+	blocked := []schedule.Slot{blackoutSlot(b)}
+	var affected []models.Booking
+	for _, booking := range bookings {
+		if _, _, overlap := schedule.AnyOverlap(toScheduleSlots(booking.Slots), blocked); overlap {
+			affected = append(affected, booking)
+		}
+	}
+	return affected, nil
+}
+
+func bookingsResponse(bookings []models.Booking) []gin.H {
+	resp := make([]gin.H, 0, len(bookings))
+	for _, b := range bookings {
+		resp = append(resp, bookingResponse(b))
+	}
+	return resp
+}
+
+// createBlackout 校验并保存休假停课，返回与之冲突的已有预约，供管理员重新安排
+func createBlackout(c *gin.Context, req CreateBlackoutRequest) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date format"})
+		return
+	}
+	endDate := startDate
+	if req.EndDate != "" {
+		if endDate, err = time.Parse("2006-01-02", req.EndDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date format"})
+			return
+		}
+	}
+	if endDate.Before(startDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be earlier than start_date"})
+		return
+	}
+	if req.StartTime != "" || req.EndTime != "" {
+		if err := (schedule.Slot{Start: req.StartTime, End: req.EndTime}).Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time range: " + err.Error()})
+			return
+		}
+	}
+	if req.CoachID != nil {
+		var coach models.Coach
+		if err := database.DB.First(&coach, *req.CoachID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+			return
+		}
+	}
+
+	blackout := models.Blackout{
+		CoachID:   req.CoachID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Reason:    req.Reason,
+		CreatedBy: userID,
+	}
+	var affected []models.Booking
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockBlackoutDays(tx, blackout); err != nil {
+			return err
+		}
+		if err := tx.Create(&blackout).Error; err != nil {
+			return err
+		}
+		var err error
+		affected, err = findAffectedBookings(tx, blackout)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blackout"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Blackout created successfully",
+		"blackout":          blackoutResponse(blackout),
+		"affected_bookings": bookingsResponse(affected),
+	})
+}
+
+// lockBlackoutDays 按教练、日期顺序锁定休假停课覆盖的所有教练日，全校停课锁定所有教练；
+// 与创建预约使用同一把锁，保证同时提交的预约要么看到休假停课，要么出现在受影响的预约中
+func lockBlackoutDays(tx *gorm.DB, blackout models.Blackout) error {
+	var coachIDs []uint
+	if blackout.CoachID != nil {
+		coachIDs = []uint{*blackout.CoachID}
+	} else if err := tx.Model(&models.Coach{}).Order("id").Pluck("id", &coachIDs).Error; err != nil {
+		return err
+	}
+	for _, coachID := range coachIDs {
+		for d := blackout.StartDate; !d.After(blackout.EndDate); d = d.AddDate(0, 0, 1) {
+			if err := lockCoachDay(tx, coachID, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ListBlackoutsHandler 管理员查询休假停课，可按 coach_id 和 from/to 日期过滤
+func ListBlackoutsHandler(c *gin.Context) {
+	db := database.DB
+	if coachID := c.Query("coach_id"); coachID != "" {
+		db = db.Where("coach_id = ? OR coach_id IS NULL", coachID)
+	}
+	if from := c.Query("from"); from != "" {
+		db = db.Where("end_date >= ?", from)
+	}
+	if to := c.Query("to"); to != "" {
+		db = db.Where("start_date <= ?", to)
+	}
+	var blackouts []models.Blackout
+	if err := db.Order("start_date").Find(&blackouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blackouts"})
+		return
+	}
+	resp := make([]gin.H, 0, len(blackouts))
+	for _, b := range blackouts {
+		resp = append(resp, blackoutResponse(b))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateBlackoutHandler 管理员为某个教练或全校创建休假停课
+func CreateBlackoutHandler(c *gin.Context) {
+	var req CreateBlackoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	createBlackout(c, req)
+}
+
+// GetBlackoutBookingsHandler 管理员查看与某个休假停课冲突的预约
+func GetBlackoutBookingsHandler(c *gin.Context) {
+	var blackout models.Blackout
+	if err := database.DB.First(&blackout, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blackout not found"})
+		return
+	}
+	affected, err := findAffectedBookings(database.DB, blackout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+	c.JSON(http.StatusOK, bookingsResponse(affected))
+}
+
+// DeleteBlackoutHandler 管理员删除休假停课
+func DeleteBlackoutHandler(c *gin.Context) {
+	if err := database.DB.Delete(&models.Blackout{}, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blackout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout deleted successfully"})
+}
+
+// ListOwnBlackoutsHandler 教练查看自己的休假以及全校停课
+func ListOwnBlackoutsHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	var blackouts []models.Blackout
+	if err := database.DB.Where("coach_id = ? OR coach_id IS NULL", coach.ID).Order("start_date").Find(&blackouts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blackouts"})
+		return
+	}
+	resp := make([]gin.H, 0, len(blackouts))
+	for _, b := range blackouts {
+		resp = append(resp, blackoutResponse(b))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateOwnBlackoutHandler 教练为自己登记休假
+func CreateOwnBlackoutHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	var req CreateBlackoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	// 教练只能为自己登记，忽略请求中的 coach_id
+	req.CoachID = &coach.ID
+	createBlackout(c, req)
+}
+
+// DeleteOwnBlackoutHandler 教练删除自己登记的休假
+func DeleteOwnBlackoutHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	result := database.DB.Where("id = ? AND coach_id = ?", c.Param("id"), coach.ID).Delete(&models.Blackout{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blackout"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blackout not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Blackout deleted successfully"})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// calendarGroup 是日历视图中一位教练一天或一周的预约
+type calendarGroup struct {
+	Start         string  `json:"start"`
+	End           string  `json:"end"`
+	BookingCount  int     `json:"booking_count"`
+	BookedMinutes int     `json:"booked_minutes"`
+	Bookings      []gin.H `json:"bookings"`
+}
+
+// weekStart 返回日期所在周的周一
+func weekStart(d time.Time) time.Time {
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+// BookingCalendarHandler 按教练分组返回 from 到 to 之间的预约，供日历页面一次加载一个月，
+// group=day（默认）按天分组，group=week 按周（周一开始）分组；其他筛选条件与 ListBookingsHandler 相同
+func BookingCalendarHandler(c *gin.Context) {
+	group := c.DefaultQuery("group", "day")
+	if group != "day" && group != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group must be day or week"})
+		return
+	}
+	maxDays := config.Cfg.Schedule.MaxRangeDays
+	if maxDays <= 0 {
+		maxDays = 62
+	}
+	from, to, ok := parseDateRange(c, maxDays)
+	if !ok {
+		return
+	}
+	db, ok := bookingQuery(c)
+	if !ok {
+		return
+	}
+	var bookings []models.Booking
+	if err := db.Preload("Slots").Preload("Course").Preload("GroupSession").
+		Where("booking_date BETWEEN ? AND ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("coach_id, booking_date, time_slot, id").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	coachIDs := []uint{}
+	byCoach := make(map[uint][]models.Booking)
+	for _, b := range bookings {
+		if _, ok := byCoach[b.CoachID]; !ok {
+			coachIDs = append(coachIDs, b.CoachID)
+		}
+		byCoach[b.CoachID] = append(byCoach[b.CoachID], b)
+	}
+	var coaches []models.Coach
+	if len(coachIDs) > 0 {
+		if err := database.DB.Select("id", "name").Where("id IN ?", coachIDs).Find(&coaches).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+			return
+		}
+	}
+	names := make(map[uint]string, len(coaches))
+	for _, co := range coaches {
+		names[co.ID] = co.Name
+	}
+	sort.Slice(coachIDs, func(i, j int) bool { return coachIDs[i] < coachIDs[j] })
+
+	result := make([]gin.H, 0, len(coachIDs))
+	for _, coachID := range coachIDs {
+		groups := []*calendarGroup{}
+		var current *calendarGroup
+		for _, b := range byCoach[coachID] {
+			start, end := b.BookingDate, b.BookingDate
+			if group == "week" {
+				start = weekStart(b.BookingDate)
+				end = start.AddDate(0, 0, 6)
+			}
+			key := start.Format("2006-01-02")
+			if current == nil || current.Start != key {
+				current = &calendarGroup{Start: key, End: end.Format("2006-01-02")}
+				groups = append(groups, current)
+			}
+			current.BookingCount++
+			current.BookedMinutes += schedule.Duration(toScheduleSlots(b.Slots))
+			current.Bookings = append(current.Bookings, bookingResponse(b))
+		}
+		result = append(result, gin.H{
+			"coach_id":   coachID,
+			"coach_name": names[coachID],
+			"groups":     groups,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from.Format("2006-01-02"),
+		"to":      to.Format("2006-01-02"),
+		"group":   group,
+		"coaches": result,
+	})
+}
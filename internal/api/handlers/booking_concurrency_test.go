@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 并发测试依赖行锁，需要真实的 MySQL，例如：
+// CLASSORDER_TEST_DSN="root:pass@tcp(127.0.0.1:3306)/classorder_test?charset=utf8mb4&parseTime=True&loc=Local" go test ./internal/api/handlers/
+func setupConcurrencyTest(t *testing.T) (*gin.Engine, models.Coach) {
+	dsn := os.Getenv("CLASSORDER_TEST_DSN")
+	if dsn == "" {
+		t.Skip("CLASSORDER_TEST_DSN is not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		DisableForeignKeyConstraintWhenMigrating: true,
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connect database: %v", err)
+	}
+	if err := database.AutoMigrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	database.DB = db
+	config.Cfg = &config.Config{}
+
+	suffix := time.Now().UnixNano()
+	user := models.User{Username: fmt.Sprintf("concurrency-%d", suffix), PasswordHash: "-", Role: "coach"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	coach := models.Coach{UserID: user.ID, Name: "Concurrency Coach"}
+	if err := db.Create(&coach).Error; err != nil {
+		t.Fatalf("create coach: %v", err)
+	}
+	t.Cleanup(func() {
+		var ids []uint
+		db.Model(&models.Booking{}).Where("coach_id = ?", coach.ID).Pluck("id", &ids)
+		if len(ids) > 0 {
+			db.Where("booking_id IN ?", ids).Delete(&models.BookingSlot{})
+			db.Where("booking_id IN ?", ids).Delete(&models.BookingStatusChange{})
+			db.Delete(&models.Booking{}, ids)
+		}
+		db.Where("coach_id = ?", coach.ID).Delete(&models.CoachDayLock{})
+		db.Delete(&coach)
+		db.Delete(&user)
+	})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", float64(user.ID))
+		c.Set("role", "admin")
+	})
+	r.POST("/bookings", CreateBookingHandler)
+	r.PUT("/bookings/:id", UpdateBookingHandler)
+	return r, coach
+}
+
+// fireParallel 同时发出 n 个请求，返回各请求的状态码
+func fireParallel(r *gin.Engine, n int, newRequest func(i int) *http.Request) []int {
+	codes := make([]int, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := newRequest(i)
+			<-start
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			codes[i] = w.Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return codes
+}
+
+func jsonRequest(method, path string, body interface{}) *http.Request {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func countCodes(codes []int, code int) int {
+	n := 0
+	for _, c := range codes {
+		if c == code {
+			n++
+		}
+	}
+	return n
+}
+
+func TestConcurrentCreateSameSlot(t *testing.T) {
+	r, coach := setupConcurrencyTest(t)
+	date := time.Now().AddDate(0, 0, 30).Format("2006-01-02")
+
+	const n = 10
+	codes := fireParallel(r, n, func(i int) *http.Request {
+		return jsonRequest(http.MethodPost, "/bookings", gin.H{
+			"student_name": fmt.Sprintf("student-%d", i),
+			"coach_id":     coach.ID,
+			"date":         date,
+			"time_slots":   "10:00-11:00",
+		})
+	})
+
+	if got := countCodes(codes, http.StatusCreated); got != 1 {
+		t.Fatalf("expected exactly one booking to succeed, got %d (codes %v)", got, codes)
+	}
+	if got := countCodes(codes, http.StatusConflict); got != n-1 {
+		t.Fatalf("expected %d conflicts, got %d (codes %v)", n-1, got, codes)
+	}
+	var count int64
+	database.DB.Model(&models.Booking{}).Where("coach_id = ? AND booking_date = ?", coach.ID, date).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 booking in database, got %d", count)
+	}
+}
+
+func TestConcurrentUpdateToSameSlot(t *testing.T) {
+	r, coach := setupConcurrencyTest(t)
+	date := time.Now().AddDate(0, 0, 31).Format("2006-01-02")
+
+	const n = 5
+	ids := make([]uint, n)
+	for i := 0; i < n; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, jsonRequest(http.MethodPost, "/bookings", gin.H{
+			"student_name": fmt.Sprintf("student-%d", i),
+			"coach_id":     coach.ID,
+			"date":         date,
+			"time_slots":   fmt.Sprintf("%02d:00-%02d:30", 9+i, 9+i),
+		}))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create booking %d: status %d, body %s", i, w.Code, w.Body.String())
+		}
+		var resp struct {
+			Booking struct {
+				ID uint `json:"id"`
+			} `json:"booking"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		ids[i] = resp.Booking.ID
+	}
+
+	codes := fireParallel(r, n, func(i int) *http.Request {
+		return jsonRequest(http.MethodPut, fmt.Sprintf("/bookings/%d", ids[i]), gin.H{"time_slots": "16:00-17:00"})
+	})
+
+	if got := countCodes(codes, http.StatusOK); got != 1 {
+		t.Fatalf("expected exactly one update to succeed, got %d (codes %v)", got, codes)
+	}
+	var count int64
+	database.DB.Model(&models.BookingSlot{}).
+		Joins("JOIN bookings ON bookings.id = booking_slots.booking_id").
+		Where("bookings.coach_id = ? AND bookings.booking_date = ? AND booking_slots.start_time = ?", coach.ID, date, "16:00").
+		Count(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 booking at 16:00 in database, got %d", count)
+	}
+}
@@ -3,45 +3,298 @@ package handlers
 import (
 	"classOrder-backend/internal/database"
 	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"time"
 	"strings"
-	"errors"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
-	"log"
 )
 
+// CreateBookingRequest 定义了创建预约的请求结构
+// 时间段优先使用结构化的 slots，旧版前端仍可提交逗号分隔的 time_slots 字符串
 type CreateBookingRequest struct {
-	StudentName string `json:"student_name" binding:"required"`
-	CoachID     uint   `json:"coach_id" binding:"required"`
-	Date        string `json:"date" binding:"required"` // YYYY-MM-DD
-	TimeSlots   string `json:"time_slots" binding:"required"`
+	StudentName   string          `json:"student_name"` // 提供 student_id 时可省略，默认为学员姓名
+	StudentID     *uint           `json:"student_id"`   // 可选，关联已有学员
+	CoachID       uint            `json:"coach_id" binding:"required"`
+	Date          string          `json:"date" binding:"required"` // YYYY-MM-DD
+	Slots         []schedule.Slot `json:"slots"`
+	TimeSlots     string          `json:"time_slots"`
+	Status        string          `json:"status"`         // 可选 pending 或 confirmed，默认 confirmed
+	CourseID      *uint           `json:"course_id"`      // 可选，关联的课程
+	StartTime     string          `json:"start_time"`     // 课程有固定时长时，可只提供开始时间
+	JoinWaitlist  bool            `json:"join_waitlist"`  // 时间段已被预约时改为登记候补
+	Contact       string          `json:"contact"`        // 候补时用于通知学员的联系方式
+	RRule         string          `json:"rrule"`          // 可选，按周重复，例如 FREQ=WEEKLY;COUNT=10
+	SkipConflicts bool            `json:"skip_conflicts"` // 重复预约时跳过冲突的日期，默认任一日期冲突即整体失败
 }
 
+// UpdateBookingRequest 定义了更新预约的请求结构，未提供的字段保持不变
 type UpdateBookingRequest struct {
-	StudentName string `json:"student_name"`
-	CoachID     uint   `json:"coach_id"`
-	Date        string `json:"date"`
-	TimeSlots   string `json:"time_slots"`
+	StudentName string          `json:"student_name"`
+	StudentID   *uint           `json:"student_id"`
+	CoachID     uint            `json:"coach_id"`
+	Date        string          `json:"date"`
+	Slots       []schedule.Slot `json:"slots"`
+	TimeSlots   string          `json:"time_slots"`
+	CourseID    optionalID      `json:"course_id"` // 传 null 或 0 表示取消关联课程
+}
+
+// optionalID 区分 JSON 中未提供的字段与显式传入的 null
+type optionalID struct {
+	Set   bool  // 请求中是否包含该字段
+	Value *uint // 为 nil 或 0 表示清空
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，字段出现即视为已提供
+func (o *optionalID) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// cleared 报告请求是否要求清空该字段
+func (o optionalID) cleared() bool {
+	return o.Set && (o.Value == nil || *o.Value == 0)
 }
 
-func parseTimeRanges(slots string) [][2]string {
-	ranges := strings.Split(slots, ",")
-	var result [][2]string
-	for _, r := range ranges {
-		r = strings.TrimSpace(r)
-		parts := strings.Split(r, "-")
-		if len(parts) == 2 {
-			result = append(result, [2]string{parts[0], parts[1]})
+var (
+	errSlotConflict = errors.New("time slot conflict")
+	errBookingFinal = errors.New("booking is in a final status")
+)
+
+// resolveSlots 从结构化时间段或旧版字符串中得到校验后的时间段
+func resolveSlots(slots []schedule.Slot, legacy string) ([]schedule.Slot, error) {
+	if len(slots) > 0 {
+		return schedule.Normalize(slots)
+	}
+	if legacy != "" {
+		return schedule.ParseSlots(legacy)
+	}
+	return nil, errors.New("时间段不能为空")
+}
+
+// resolveCourseSlots 解析时间段并校验其总时长与课程时长一致；
+// 课程有固定时长且只提供了开始时间时，自动生成时间段
+func resolveCourseSlots(course *models.Course, slots []schedule.Slot, legacy, startTime string) ([]schedule.Slot, error) {
+	if course != nil && course.DurationMinutes > 0 && len(slots) == 0 && legacy == "" && startTime != "" {
+		slot, err := schedule.SlotFrom(startTime, course.DurationMinutes)
+		if err != nil {
+			return nil, err
 		}
+		return []schedule.Slot{slot}, nil
+	}
+	resolved, err := resolveSlots(slots, legacy)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCourseDuration(course, resolved); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+// checkCourseDuration 校验时间段总时长与课程时长一致，课程未设置时长时不校验
+func checkCourseDuration(course *models.Course, slots []schedule.Slot) error {
+	if course == nil || course.DurationMinutes == 0 {
+		return nil
+	}
+	if total := schedule.Duration(slots); total != course.DurationMinutes {
+		return fmt.Errorf("课程 %s 时长为 %d 分钟，所选时间段共 %d 分钟", course.Name, course.DurationMinutes, total)
+	}
+	return nil
+}
+
+// toScheduleSlots 将数据库中的时间段转换为 schedule.Slot
+func toScheduleSlots(slots []models.BookingSlot) []schedule.Slot {
+	result := make([]schedule.Slot, 0, len(slots))
+	for _, s := range slots {
+		result = append(result, schedule.Slot{Start: s.StartTime, End: s.EndTime})
 	}
 	return result
 }
 
-func timeRangeOverlap(a, b [2]string) bool {
-	return !(a[1] <= b[0] || a[0] >= b[1])
+// toModelSlots 将 schedule.Slot 转换为待写入数据库的时间段
+func toModelSlots(slots []schedule.Slot) []models.BookingSlot {
+	result := make([]models.BookingSlot, 0, len(slots))
+	for _, s := range slots {
+		result = append(result, models.BookingSlot{StartTime: s.Start, EndTime: s.End})
+	}
+	return result
+}
+
+// lockCoachDay 锁定教练某一天的锁行，不存在时先插入。
+// 仅对已有预约 SELECT ... FOR UPDATE 无法阻止两个事务同时插入同一天的第一条预约，
+// 所以所有写入预约的事务都先锁定这一行，直到事务结束
+func lockCoachDay(tx *gorm.DB, coachID uint, date time.Time) error {
+	lock := models.CoachDayLock{CoachID: coachID, Date: date}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&lock).Error; err != nil {
+		return err
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("coach_id = ? AND date = ?", coachID, date.Format("2006-01-02")).
+		First(&lock).Error
+}
+
+// checkSlotConflict 检查教练当天的其他有效预约是否与给定时间段重叠，已取消的预约不参与检查；
+// 时间段同样使用加锁读取，保证读到其他事务已提交的最新数据
+func checkSlotConflict(tx *gorm.DB, coachID uint, date time.Time, excludeID uint, slots []schedule.Slot) error {
+	var existing []models.Booking
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Slots", func(db *gorm.DB) *gorm.DB { return db.Clauses(clause.Locking{Strength: "UPDATE"}) }).
+		Where("coach_id = ? AND booking_date = ? AND status <> ?", coachID, date, models.BookingStatusCancelled)
+	if excludeID != 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	if err := query.Find(&existing).Error; err != nil {
+		return err
+	}
+	for _, e := range existing {
+		if nr, er, ok := schedule.AnyOverlap(slots, toScheduleSlots(e.Slots)); ok {
+			log.Printf("[BookingConflict] coach_id=%d, date=%s, new=%s, exist=%s (booking %d)",
+				coachID, date.Format("2006-01-02"), nr, er, e.ID)
+			return errSlotConflict
+		}
+	}
+	return nil
+}
+
+// checkBookable 锁定教练当天后，检查时间段是否在教练的工作时间内且不与其他预约冲突
+func checkBookable(tx *gorm.DB, coachID uint, date time.Time, excludeID uint, slots []schedule.Slot) error {
+	if err := lockCoachDay(tx, coachID, date); err != nil {
+		return err
+	}
+	if err := checkAvailability(tx, coachID, date, slots); err != nil {
+		return err
+	}
+	return checkSlotConflict(tx, coachID, date, excludeID, slots)
+}
+
+// bookingErrorMessage 返回预约校验错误对应的提示，其他错误返回 false
+func bookingErrorMessage(err error) (string, bool) {
+	switch {
+	case errors.Is(err, errSlotConflict):
+		return "所选时间段已被预约，请选择其他时间段", true
+	case errors.Is(err, errOutsideAvailability):
+		return "所选时间段不在教练的工作时间内", true
+	case errors.Is(err, errInBlackout):
+		return "所选时间段处于教练休假或停课期间", true
+	}
+	return "", false
+}
+
+// respondBookingError 将预约校验错误转换为对应的HTTP响应
+func respondBookingError(c *gin.Context, err error, fallback string) {
+	if msg, ok := bookingErrorMessage(err); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+}
+
+// bookingResponse 返回前端需要的预约字段，同时提供结构化时间段和旧版字符串
+func bookingResponse(b models.Booking) gin.H {
+	slots := toScheduleSlots(b.Slots)
+	courseName := ""
+	if b.Course != nil {
+		courseName = b.Course.Name
+	}
+	var groupSessionID *uint
+	if b.GroupSession != nil {
+		groupSessionID = &b.GroupSession.ID
+	}
+	return gin.H{
+		"id":               b.ID,
+		"coach_id":         b.CoachID,
+		"date":             b.BookingDate.Format("2006-01-02"),
+		"slots":            slots,
+		"time_slots":       b.TimeSlot,
+		"student_name":     b.ClientInfo,
+		"status":           b.Status,
+		"status_reason":    b.StatusReason,
+		"course_id":        b.CourseID,
+		"course_name":      courseName,
+		"price":            b.Price,
+		"duration_minutes": schedule.Duration(slots),
+		"group_session_id": groupSessionID,
+		"series_id":        b.SeriesID,
+		"series_exception": b.SeriesException,
+		"student_id":       b.StudentID,
+	}
+}
+
+// newBookingFromRequest 校验创建预约的请求并构造预约，返回的错误可直接展示给调用方
+func newBookingFromRequest(req CreateBookingRequest) (models.Booking, error) {
+	bookingDate, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return models.Booking{}, errors.New("Invalid date format")
+	}
+	studentName := strings.TrimSpace(req.StudentName)
+	if req.StudentID != nil {
+		var student models.Student
+		if err := database.DB.First(&student, *req.StudentID).Error; err != nil {
+			return models.Booking{}, errors.New("Student not found")
+		}
+		if studentName == "" {
+			studentName = student.Name
+		}
+	}
+	if studentName == "" {
+		return models.Booking{}, errors.New("student_name or student_id is required")
+	}
+	var course *models.Course
+	if req.CourseID != nil {
+		if course, err = loadBookableCourse(*req.CourseID); err != nil {
+			return models.Booking{}, errors.New("Course not found or no longer available")
+		}
+	}
+	slots, err := resolveCourseSlots(course, req.Slots, req.TimeSlots, req.StartTime)
+	if err != nil {
+		return models.Booking{}, errors.New("Invalid time slots: " + err.Error())
+	}
+	status := models.BookingStatusConfirmed
+	if req.Status != "" {
+		if req.Status != models.BookingStatusPending && req.Status != models.BookingStatusConfirmed {
+			return models.Booking{}, errors.New("New bookings must be pending or confirmed")
+		}
+		status = req.Status
+	}
+
+	booking := models.Booking{
+		CoachID:     req.CoachID,
+		BookingDate: bookingDate,
+		TimeSlot:    schedule.Format(slots),
+		ClientInfo:  studentName,
+		StudentID:   req.StudentID,
+		Status:      status,
+		Slots:       toModelSlots(slots),
+	}
+	if course != nil {
+		booking.CourseID = &course.ID
+		booking.Price = course.Price
+		booking.Course = course
+	}
+	return booking, nil
+}
+
+// insertBooking 在事务中校验工作时间与冲突后写入预约，并记录初始状态
+func insertBooking(tx *gorm.DB, booking *models.Booking, createdBy uint) error {
+	if err := checkBookable(tx, booking.CoachID, booking.BookingDate, 0, toScheduleSlots(booking.Slots)); err != nil {
+		return err
+	}
+	if err := tx.Omit("Course").Create(booking).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.BookingStatusChange{BookingID: booking.ID, ToStatus: booking.Status, ChangedBy: createdBy}).Error
 }
 
 // CreateBookingHandler 创建预约
@@ -51,144 +304,288 @@ func CreateBookingHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
 		return
 	}
-	bookingDate, err := time.Parse("2006-01-02", req.Date)
+	userID, ok := currentUserID(c)
+	if !ok || !requireOwnCoach(c, req.CoachID) {
+		return
+	}
+	booking, err := newBookingFromRequest(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.RRule != "" {
+		createBookingSeries(c, req, booking, userID)
 		return
 	}
 
-	log.Printf("[CreateBooking] coach_id=%d, date=%s, time_slots=%s", req.CoachID, bookingDate.Format("2006-01-02"), req.TimeSlots)
+	log.Printf("[CreateBooking] coach_id=%d, date=%s, slots=%s", booking.CoachID, booking.BookingDate.Format("2006-01-02"), booking.TimeSlot)
 
 	// 并发锁+冲突检测
 	err = database.DB.Transaction(func(tx *gorm.DB) error {
-		var existing []models.Booking
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("coach_id = ? AND booking_date = ?", req.CoachID, bookingDate).Find(&existing).Error
-		if err != nil {
-			return err
-		}
-		log.Printf("[CreateBooking] existing bookings: %+v", existing)
-		newRanges := parseTimeRanges(req.TimeSlots)
-		for _, e := range existing {
-			existRanges := parseTimeRanges(e.TimeSlot)
-			for _, nr := range newRanges {
-				for _, er := range existRanges {
-					if timeRangeOverlap(nr, er) {
-						c.JSON(http.StatusConflict, gin.H{"error": "所选时间段已被预约，请选择其他时间段"})
-						log.Printf("[CreateBooking] conflict: new=%v, exist=%v", nr, er)
-						return errors.New("time slot conflict")
-					}
-				}
-			}
-		}
-		booking := models.Booking{
-			CoachID:     req.CoachID,
-			BookingDate: bookingDate,
-			TimeSlot:    req.TimeSlots,
-			ClientInfo:  req.StudentName,
-		}
-		if err := tx.Create(&booking).Error; err != nil {
-			return err
-		}
-		return nil
+		return insertBooking(tx, &booking, userID)
 	})
-	if err != nil {
-		if err.Error() == "time slot conflict" {
+	if errors.Is(err, errSlotConflict) && req.JoinWaitlist {
+		entry := waitlistEntryFromBooking(booking, req.Contact, userID)
+		if err := database.DB.Create(&entry).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking: " + err.Error()})
+		c.JSON(http.StatusAccepted, gin.H{"message": "所选时间段已被预约，已加入候补", "waitlist_entry": waitlistResponse(entry)})
+		return
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to create booking: "+err.Error())
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Booking created successfully"})
+	c.JSON(http.StatusCreated, gin.H{"message": "Booking created successfully", "booking": bookingResponse(booking)})
 }
 
 // UpdateBookingHandler 更新预约
 func UpdateBookingHandler(c *gin.Context) {
 	id := c.Param("id")
 	var booking models.Booking
-	if err := database.DB.First(&booking, id).Error; err != nil {
+	if err := database.DB.Preload("Slots").Preload("Course").First(&booking, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
 		return
 	}
+	if models.IsFinalBookingStatus(booking.Status) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is " + booking.Status + " and can no longer be modified"})
+		return
+	}
 	var req UpdateBookingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
 	// 记录原始信息
+	if req.StudentID != nil {
+		var student models.Student
+		if err := database.DB.First(&student, *req.StudentID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Student not found"})
+			return
+		}
+		booking.StudentID = &student.ID
+		booking.ClientInfo = student.Name
+	}
 	if req.StudentName != "" {
 		booking.ClientInfo = req.StudentName
 	}
 	if req.CoachID != 0 {
+		if !requireOwnCoach(c, req.CoachID) {
+			return
+		}
 		booking.CoachID = req.CoachID
 	}
 	if req.Date != "" {
-		if date, err := time.Parse("2006-01-02", req.Date); err == nil {
-			booking.BookingDate = date
+		date, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format"})
+			return
 		}
+		booking.BookingDate = date
 	}
-	if req.TimeSlots != "" {
-		booking.TimeSlot = req.TimeSlots
+	var courseChanged bool
+	switch {
+	case req.CourseID.cleared():
+		courseChanged = booking.CourseID != nil
+		booking.CourseID = nil
+		booking.Price = 0
+		booking.Course = nil
+	case req.CourseID.Set && (booking.CourseID == nil || *booking.CourseID != *req.CourseID.Value):
+		courseChanged = true
+		course, err := loadBookableCourse(*req.CourseID.Value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Course not found or no longer available"})
+			return
+		}
+		booking.CourseID = &course.ID
+		booking.Price = course.Price
+		booking.Course = course
 	}
-	// 冲突校验：查找同教练同天除自己外的所有预约，判断时间段是否重叠
-	var existing []models.Booking
-	database.DB.Where("coach_id = ? AND booking_date = ? AND id != ?", booking.CoachID, booking.BookingDate, booking.ID).Find(&existing)
-	newRanges := parseTimeRanges(booking.TimeSlot)
-	for _, e := range existing {
-		existRanges := parseTimeRanges(e.TimeSlot)
-		for _, nr := range newRanges {
-			for _, er := range existRanges {
-				if timeRangeOverlap(nr, er) {
-					c.JSON(http.StatusConflict, gin.H{"error": "所选时间段已被预约，请选择其他时间段"})
-					return
-				}
+	slots := toScheduleSlots(booking.Slots)
+	slotsChanged := len(req.Slots) > 0 || req.TimeSlots != ""
+	if slotsChanged {
+		var err error
+		slots, err = resolveSlots(req.Slots, req.TimeSlots)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time slots: " + err.Error()})
+			return
+		}
+		booking.TimeSlot = schedule.Format(slots)
+	}
+	if slotsChanged || courseChanged {
+		if err := checkCourseDuration(booking.Course, slots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time slots: " + err.Error()})
+			return
+		}
+	}
+	scheduleChanged := slotsChanged || req.CoachID != 0 || req.Date != ""
+	// 单独修改的系列预约之后不再跟随系列整体修改
+	if booking.SeriesID != nil {
+		booking.SeriesException = true
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 仅在教练、日期或时间段变化时校验工作时间，并查找同教练同天除自己外的所有预约，判断时间段是否重叠
+		if scheduleChanged {
+			if err := checkBookable(tx, booking.CoachID, booking.BookingDate, booking.ID, slots); err != nil {
+				return err
+			}
+		}
+		// 加载预约后到加锁前，预约可能已被其他请求取消或完成，加锁后重新检查
+		var current models.Booking
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status", "status_reason", "status_changed_at", "sequence").First(&current, booking.ID).Error; err != nil {
+			return err
+		}
+		if models.IsFinalBookingStatus(current.Status) {
+			return errBookingFinal
+		}
+		booking.Status, booking.StatusReason, booking.StatusChangedAt = current.Status, current.StatusReason, current.StatusChangedAt
+		booking.Sequence = current.Sequence + 1
+		if slotsChanged {
+			if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.BookingSlot{}).Error; err != nil {
+				return err
 			}
+			booking.Slots = toModelSlots(slots)
 		}
+		return tx.Omit("Course").Save(&booking).Error
+	})
+	if errors.Is(err, errBookingFinal) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking can no longer be modified"})
+		return
 	}
-	if err := database.DB.Save(&booking).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+	if err != nil {
+		respondBookingError(c, err, "Failed to update booking")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Booking updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Booking updated successfully", "booking": bookingResponse(booking)})
 }
 
-// DeleteBookingHandler 删除预约
+// DeleteBookingHandler 取消预约；为保留历史记录，预约不会被物理删除
 func DeleteBookingHandler(c *gin.Context) {
-	id := c.Param("id")
-	if err := database.DB.Delete(&models.Booking{}, id).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete booking"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"message": "Booking deleted successfully"})
+	transitionBooking(c, models.BookingStatusCancelled, c.Query("reason"))
 }
 
-// ListBookingsHandler 查询预约
-func ListBookingsHandler(c *gin.Context) {
-	var bookings []models.Booking
-	coachID := c.Query("coach_id")
-	dateStr := c.Query("date")
-	db := database.DB
-	if coachID != "" {
-		db = db.Where("coach_id = ?", coachID)
+// bookingQuery 根据查询参数构造预约查询，教练只能查询自己的预约，参数错误时直接写入400响应。
+// 支持 coach_id、student_id、course_id、series_id、created_by（创建预约的用户）、
+// date（单日）或 from/to（日期范围，可只提供一端）、status（逗号分隔，all 表示全部，默认不含已取消）
+// 以及 q（学员姓名或手机号）
+func bookingQuery(c *gin.Context) (*gorm.DB, bool) {
+	db := database.DB.Model(&models.Booking{})
+	// status 支持逗号分隔的多个状态，all 表示全部；默认不返回已取消的预约
+	switch statusParam := c.Query("status"); statusParam {
+	case "":
+		db = db.Where("status <> ?", models.BookingStatusCancelled)
+	case "all":
+	default:
+		statuses := strings.Split(statusParam, ",")
+		for _, st := range statuses {
+			if !models.IsValidBookingStatus(st) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status: " + st})
+				return nil, false
+			}
+		}
+		db = db.Where("status IN ?", statuses)
+	}
+
+	coachID, ok := parseIDQuery(c, "coach_id")
+	if !ok {
+		return nil, false
 	}
-	if dateStr != "" {
+	if scoped, ok := scopedCoachID(c); ok {
+		coachID = &scoped
+	}
+	if coachID != nil {
+		db = db.Where("coach_id = ?", *coachID)
+	}
+	for _, column := range []string{"series_id", "student_id", "course_id"} {
+		id, ok := parseIDQuery(c, column)
+		if !ok {
+			return nil, false
+		}
+		if id != nil {
+			db = db.Where(column+" = ?", *id)
+		}
+	}
+	createdBy, ok := parseIDQuery(c, "created_by")
+	if !ok {
+		return nil, false
+	}
+	if createdBy != nil {
+		// 创建人记录在初始状态的变更历史中
+		db = db.Where("id IN (?)", database.DB.Model(&models.BookingStatusChange{}).Select("booking_id").
+			Where("from_status = '' AND changed_by = ?", *createdBy))
+	}
+
+	if dateStr := c.Query("date"); dateStr != "" {
 		if date, err := time.Parse("2006-01-02", dateStr); err == nil {
 			db = db.Where("DATE(booking_date) = ?", date.Format("2006-01-02"))
 		}
 	}
-	if err := db.Find(&bookings).Error; err != nil {
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<="}} {
+		s := c.Query(bound.param)
+		if s == "" {
+			continue
+		}
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + bound.param + " date format"})
+			return nil, false
+		}
+		db = db.Where("booking_date "+bound.op+" ?", d.Format("2006-01-02"))
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		prefix := normalizePhone(q) + "%"
+		db = db.Where("client_info LIKE ? OR student_id IN (?)", "%"+q+"%",
+			database.DB.Model(&models.Student{}).Select("id").Where("phone LIKE ? OR guardian_phone LIKE ?", prefix, prefix))
+	}
+	return db.Session(&gorm.Session{}), true
+}
+
+// ListBookingsHandler 查询预约，筛选条件见 bookingQuery
+// sort 可选 date（默认，按上课日期）、date_desc、newest（按创建时间倒序）、coach（按教练再按日期）；
+// page/page_size 分页，总数通过 X-Total-Count 返回
+func ListBookingsHandler(c *gin.Context) {
+	page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	db, ok := bookingQuery(c)
+	if !ok {
+		return
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+	switch c.Query("sort") {
+	case "", "date":
+		db = db.Order("booking_date, time_slot, id")
+	case "date_desc":
+		db = db.Order("booking_date DESC, time_slot DESC, id DESC")
+	case "newest":
+		db = db.Order("created_at DESC, id DESC")
+	case "coach":
+		db = db.Order("coach_id, booking_date, time_slot, id")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort"})
+		return
+	}
+	var bookings []models.Booking
+	if err := page.apply(db.Preload("Slots").Preload("Course").Preload("GroupSession")).Find(&bookings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
 		return
 	}
 	// 返回前端需要的字段
-	var resp []gin.H
+	resp := make([]gin.H, 0, len(bookings))
 	for _, b := range bookings {
-		resp = append(resp, gin.H{
-			"id":           b.ID,
-			"coach_id":     b.CoachID,
-			"date":         b.BookingDate.Format("2006-01-02"),
-			"time_slots":   b.TimeSlot,
-			"student_name": b.ClientInfo,
-		})
+		resp = append(resp, bookingResponse(b))
 	}
+	setPageHeaders(c, page, total)
 	c.JSON(http.StatusOK, resp)
-} 
\ No newline at end of file
+}
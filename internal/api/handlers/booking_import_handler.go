@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/ical"
+	"classOrder-backend/internal/models"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 导入文件的大小和行数上限
+const (
+	maxImportBytes = 2 << 20
+	maxImportRows  = 1000
+)
+
+// errImportDryRun 用于在试运行结束时回滚事务
+var errImportDryRun = errors.New("dry run")
+
+// importRow 是导入文件中的一条预约及其校验结果
+type importRow struct {
+	Row           int    `json:"row"` // CSV 中的行号（含表头），或 ICS 中的事件序号
+	UID           string `json:"uid,omitempty"`
+	CoachUsername string `json:"coach_username"`
+	Date          string `json:"date"`
+	TimeSlots     string `json:"time_slots"`
+	StudentName   string `json:"student_name"`
+	Phone         string `json:"phone,omitempty"`
+	Course        string `json:"course,omitempty"`
+	Status        string `json:"status,omitempty"`
+	Result        string `json:"result"` // ok、error 或 skipped
+	Error         string `json:"error,omitempty"`
+	BookingID     uint   `json:"booking_id,omitempty"` // 实际导入后才有值
+
+	booking models.Booking
+}
+
+func (r *importRow) fail(msg string) {
+	r.Result = "error"
+	r.Error = msg
+}
+
+// parseImportCSV 解析 CSV，第一行为表头，必需列为 coach_username、date、time_slots、student，
+// 可选列为 phone（关联已有学员）、course（课程名称）和 status（pending 或 confirmed）
+func parseImportCSV(r io.Reader) ([]*importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("CSV header is required")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, required := range []string{"coach_username", "date", "time_slots", "student"} {
+		if _, ok := columns[required]; !ok {
+			return nil, errors.New("CSV is missing column: " + required)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []*importRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %v", line, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		rows = append(rows, &importRow{
+			Row:           line,
+			CoachUsername: field(record, "coach_username"),
+			Date:          field(record, "date"),
+			TimeSlots:     field(record, "time_slots"),
+			StudentName:   field(record, "student"),
+			Phone:         field(record, "phone"),
+			Course:        field(record, "course"),
+			Status:        field(record, "status"),
+		})
+	}
+	return rows, nil
+}
+
+// parseImportICS 解析 ICS，每个 VEVENT 为一条预约，SUMMARY 作为学员姓名；
+// ICS 中没有教练信息，所有事件都导入到 coachUsername 名下，已取消的事件会跳过
+func parseImportICS(r io.Reader, coachUsername string) ([]*importRow, error) {
+	events, err := ical.Parse(r, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]*importRow, 0, len(events))
+	for i, e := range events {
+		start, end := e.Start.In(time.Local), e.End.In(time.Local)
+		row := &importRow{
+			Row:           i + 1,
+			UID:           e.UID,
+			CoachUsername: coachUsername,
+			Date:          start.Format("2006-01-02"),
+			TimeSlots:     start.Format("15:04") + "-" + end.Format("15:04"),
+			StudentName:   strings.TrimSpace(e.Summary),
+		}
+		switch {
+		case e.Status == ical.StatusCancelled:
+			row.Result = "skipped"
+			row.Error = "Event is cancelled"
+		case e.AllDay:
+			row.fail("All-day events have no time slots")
+		case !end.After(start) || end.Format("2006-01-02") != row.Date:
+			row.fail("Event must start and end on the same day")
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// prepareImportRows 查找教练、课程和学员，并按 CreateBookingHandler 的规则构造预约；
+// 电话匹配到多个学员的行报告为错误
+func prepareImportRows(rows []*importRow) error {
+	var usernames, courseNames []string
+	for _, r := range rows {
+		usernames = append(usernames, r.CoachUsername)
+		if r.Course != "" {
+			courseNames = append(courseNames, r.Course)
+		}
+	}
+	var coaches []models.Coach
+	if err := database.DB.Preload("User").
+		Where("user_id IN (?)", database.DB.Model(&models.User{}).Select("id").Where("username IN ?", usernames)).
+		Find(&coaches).Error; err != nil {
+		return err
+	}
+	coachIDs := make(map[string]uint, len(coaches))
+	for _, co := range coaches {
+		coachIDs[co.User.Username] = co.ID
+	}
+	courseIDs := make(map[string]uint)
+	if len(courseNames) > 0 {
+		var courses []models.Course
+		if err := database.DB.Where("name IN ? AND active = ?", courseNames, true).Find(&courses).Error; err != nil {
+			return err
+		}
+		for _, course := range courses {
+			courseIDs[course.Name] = course.ID
+		}
+	}
+
+	for _, r := range rows {
+		if r.Result != "" {
+			continue
+		}
+		coachID, ok := coachIDs[r.CoachUsername]
+		if !ok {
+			r.fail("Coach not found: " + r.CoachUsername)
+			continue
+		}
+		req := CreateBookingRequest{
+			CoachID:     coachID,
+			Date:        r.Date,
+			TimeSlots:   r.TimeSlots,
+			StudentName: r.StudentName,
+			Status:      r.Status,
+		}
+		if r.Course != "" {
+			id, ok := courseIDs[r.Course]
+			if !ok {
+				r.fail("Course not found or no longer available: " + r.Course)
+				continue
+			}
+			req.CourseID = &id
+		}
+		if r.Phone != "" {
+			phone := normalizePhone(r.Phone)
+			var students []models.Student
+			if err := database.DB.Select("id").Where("phone = ? OR guardian_phone = ?", phone, phone).
+				Limit(2).Find(&students).Error; err != nil {
+				return err
+			}
+			// 多个学员使用同一电话时无法判断是谁，不自动关联
+			if len(students) > 1 {
+				r.fail("Phone matches more than one student: " + r.Phone)
+				continue
+			}
+			if len(students) == 1 {
+				req.StudentID = &students[0].ID
+			}
+		}
+		booking, err := newBookingFromRequest(req)
+		if err != nil {
+			r.fail(err.Error())
+			continue
+		}
+		r.booking = booking
+		r.Result = "ok"
+	}
+	return nil
+}
+
+// ImportBookingsHandler 从 CSV 或 ICS 文件导入已有预约。默认只试运行，返回每一行的校验结果；
+// dry_run=false 时在同一个事务中写入全部校验通过的行。每一行都按照 CreateBookingHandler 的规则
+// 检查工作时间、休假和时间冲突，文件内的行之间同样不能冲突
+func ImportBookingsHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	dryRun := c.DefaultQuery("dry_run", "true") != "false"
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	if file.Size > maxImportBytes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is too large"})
+		return
+	}
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer f.Close()
+
+	var rows []*importRow
+	switch format {
+	case "csv":
+		rows, err = parseImportCSV(f)
+	case "ics":
+		coachUsername := strings.TrimSpace(c.PostForm("coach_username"))
+		if coachUsername == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "coach_username is required for ICS imports"})
+			return
+		}
+		rows, err = parseImportICS(f, coachUsername)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ics"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file: " + err.Error()})
+		return
+	}
+	if len(rows) > maxImportRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d rows can be imported at once", maxImportRows)})
+		return
+	}
+	if err := prepareImportRows(rows); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate import"})
+		return
+	}
+
+	// 按教练和日期顺序写入，与其他事务的加锁顺序保持一致
+	valid := make([]*importRow, 0, len(rows))
+	for _, r := range rows {
+		if r.Result == "ok" {
+			valid = append(valid, r)
+		}
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		a, b := valid[i].booking, valid[j].booking
+		if a.CoachID != b.CoachID {
+			return a.CoachID < b.CoachID
+		}
+		return a.BookingDate.Before(b.BookingDate)
+	})
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, r := range valid {
+			// 每一行使用保存点，校验失败只回滚该行
+			err := tx.Transaction(func(tx *gorm.DB) error {
+				return insertBooking(tx, &r.booking, userID)
+			})
+			if msg, ok := bookingErrorMessage(err); ok {
+				r.fail(msg)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if !dryRun {
+				r.BookingID = r.booking.ID
+			}
+		}
+		if dryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		log.Printf("[BookingImport] failed to import bookings: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import bookings"})
+		return
+	}
+
+	counts := map[string]int{"ok": 0, "error": 0, "skipped": 0}
+	for _, r := range rows {
+		counts[r.Result]++
+	}
+	imported := 0
+	if !dryRun {
+		imported = counts["ok"]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":  dryRun,
+		"total":    len(rows),
+		"valid":    counts["ok"],
+		"invalid":  counts["error"],
+		"skipped":  counts["skipped"],
+		"imported": imported,
+		"rows":     rows,
+	})
+}
@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSeriesOccurrences 限制一个系列最多展开的次数，约为一年的每周课程
+const maxSeriesOccurrences = 52
+
+var (
+	errSeriesConflict     = errors.New("booking series has conflicting occurrences")
+	errSeriesInvalidSlots = errors.New("invalid time slots")
+)
+
+// UpdateBookingSeriesRequest 定义了整体修改系列预约的请求结构，未提供的字段保持不变
+type UpdateBookingSeriesRequest struct {
+	StudentName       string          `json:"student_name"`
+	Slots             []schedule.Slot `json:"slots"`
+	TimeSlots         string          `json:"time_slots"`
+	From              string          `json:"from"`               // 只修改该日期（含）之后的预约，默认今天
+	IncludeExceptions bool            `json:"include_exceptions"` // 是否同时覆盖单独修改过的预约
+}
+
+// CancelBookingSeriesRequest 定义了整体取消系列预约的请求结构
+type CancelBookingSeriesRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	From   string `json:"from"` // 只取消该日期（含）之后的预约，默认今天
+}
+
+func seriesResponse(s models.BookingSeries) gin.H {
+	return gin.H{
+		"id":           s.ID,
+		"coach_id":     s.CoachID,
+		"rrule":        s.RRule,
+		"start_date":   s.StartDate.Format("2006-01-02"),
+		"student_name": s.StudentName,
+		"created_at":   s.CreatedAt,
+		"bookings":     bookingsResponse(s.Bookings),
+	}
+}
+
+// occurrenceConflict 描述系列中无法安排的一次预约
+func occurrenceConflict(b models.Booking, err error) gin.H {
+	msg, _ := bookingErrorMessage(err)
+	conflict := gin.H{"date": b.BookingDate.Format("2006-01-02"), "error": msg}
+	if b.ID != 0 {
+		conflict["booking_id"] = b.ID
+	}
+	return conflict
+}
+
+// parseSeriesFrom 解析系列操作的起始日期，未提供时为今天
+func parseSeriesFrom(from string) (time.Time, error) {
+	if from == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Parse("2006-01-02", from)
+}
+
+// createBookingSeries 按重复规则在同一事务中展开并写入每次预约；
+// 有冲突的日期会在响应中列出，除非 skip_conflicts 为 true，否则整个系列都不会创建
+func createBookingSeries(c *gin.Context, req CreateBookingRequest, template models.Booking, userID uint) {
+	rule, err := schedule.ParseRRule(req.RRule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+	dates, err := rule.Occurrences(template.BookingDate, maxSeriesOccurrences)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rrule: " + err.Error()})
+		return
+	}
+	if len(dates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "重复规则没有产生任何日期"})
+		return
+	}
+
+	log.Printf("[CreateBookingSeries] coach_id=%d, rrule=%s, occurrences=%d", template.CoachID, req.RRule, len(dates))
+
+	series := models.BookingSeries{
+		CoachID:     template.CoachID,
+		RRule:       req.RRule,
+		StartDate:   template.BookingDate,
+		StudentName: template.ClientInfo,
+		CreatedBy:   userID,
+	}
+	slots := toScheduleSlots(template.Slots)
+	conflicts := []gin.H{}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Bookings").Create(&series).Error; err != nil {
+			return err
+		}
+		for _, date := range dates {
+			booking := template
+			booking.BookingDate = date
+			booking.SeriesID = &series.ID
+			booking.Slots = toModelSlots(slots)
+
+			// 使用保存点，单次冲突时只撤销这一次预约，便于汇总所有冲突
+			savepoint := fmt.Sprintf("series_%s", date.Format("20060102"))
+			if err := tx.SavePoint(savepoint).Error; err != nil {
+				return err
+			}
+			err := insertBooking(tx, &booking, userID)
+			if _, ok := bookingErrorMessage(err); ok {
+				if err := tx.RollbackTo(savepoint).Error; err != nil {
+					return err
+				}
+				conflicts = append(conflicts, occurrenceConflict(booking, err))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			series.Bookings = append(series.Bookings, booking)
+		}
+		if len(series.Bookings) == 0 || (len(conflicts) > 0 && !req.SkipConflicts) {
+			return errSeriesConflict
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, gin.H{
+			"message":   "Booking series created successfully",
+			"series":    seriesResponse(series),
+			"conflicts": conflicts,
+		})
+	case errors.Is(err, errSeriesConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "部分日期无法预约，系列预约未创建", "conflicts": conflicts})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking series"})
+	}
+}
+
+// lockSeriesBookings 锁定系列中 from 之后仍可修改的预约；
+// 与创建预约一致，先按日期顺序锁定涉及的教练日，再锁定预约本身
+func lockSeriesBookings(tx *gorm.DB, seriesID uint, from time.Time) ([]models.Booking, error) {
+	query := func() *gorm.DB {
+		return tx.Where("series_id = ? AND booking_date >= ? AND status IN ?", seriesID, from.Format("2006-01-02"),
+			[]string{models.BookingStatusPending, models.BookingStatusConfirmed}).
+			Order("booking_date")
+	}
+	var days []models.Booking
+	if err := query().Select("id", "coach_id", "booking_date").Find(&days).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range days {
+		if err := lockCoachDay(tx, d.CoachID, d.BookingDate); err != nil {
+			return nil, err
+		}
+	}
+	var bookings []models.Booking
+	err := query().Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Slots").Preload("Course").Find(&bookings).Error
+	return bookings, err
+}
+
+func loadSeries(id string) (models.BookingSeries, error) {
+	var series models.BookingSeries
+	err := database.DB.Preload("Bookings", func(db *gorm.DB) *gorm.DB { return db.Order("booking_date") }).
+		Preload("Bookings.Slots").Preload("Bookings.Course").
+		First(&series, id).Error
+	return series, err
+}
+
+// GetBookingSeriesHandler 获取系列信息及其全部预约
+func GetBookingSeriesHandler(c *gin.Context) {
+	series, err := loadSeries(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+		return
+	}
+	c.JSON(http.StatusOK, seriesResponse(series))
+}
+
+// UpdateBookingSeriesHandler 整体修改系列中 from 之后的预约，单独修改过的预约默认保持不变；
+// 任一预约无法改到新时间段时整体失败并列出冲突
+func UpdateBookingSeriesHandler(c *gin.Context) {
+	seriesID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req UpdateBookingSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	from, err := parseSeriesFrom(req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	var slots []schedule.Slot
+	slotsChanged := len(req.Slots) > 0 || req.TimeSlots != ""
+	if slotsChanged {
+		if slots, err = resolveSlots(req.Slots, req.TimeSlots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time slots: " + err.Error()})
+			return
+		}
+	}
+
+	conflicts := []gin.H{}
+	var updated []models.Booking
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var series models.BookingSeries
+		if err := tx.First(&series, seriesID).Error; err != nil {
+			return err
+		}
+		bookings, err := lockSeriesBookings(tx, seriesID, from)
+		if err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			if booking.SeriesException && !req.IncludeExceptions {
+				continue
+			}
+			if req.StudentName != "" {
+				booking.ClientInfo = req.StudentName
+			}
+			if slotsChanged {
+				if err := checkCourseDuration(booking.Course, slots); err != nil {
+					return fmt.Errorf("%w: %v", errSeriesInvalidSlots, err)
+				}
+				if err := checkBookable(tx, booking.CoachID, booking.BookingDate, booking.ID, slots); err != nil {
+					if _, ok := bookingErrorMessage(err); ok {
+						conflicts = append(conflicts, occurrenceConflict(booking, err))
+						continue
+					}
+					return err
+				}
+				if err := tx.Where("booking_id = ?", booking.ID).Delete(&models.BookingSlot{}).Error; err != nil {
+					return err
+				}
+				booking.TimeSlot = schedule.Format(slots)
+				booking.Slots = toModelSlots(slots)
+			}
+			booking.SeriesException = false
+			booking.Sequence++
+			if err := tx.Omit("Course").Save(&booking).Error; err != nil {
+				return err
+			}
+			updated = append(updated, booking)
+		}
+		if len(conflicts) > 0 {
+			return errSeriesConflict
+		}
+		// 从系列开头起修改学员时，同步系列本身的学员名称
+		if req.StudentName != "" && !from.After(series.StartDate) {
+			return tx.Model(&series).Update("student_name", req.StudentName).Error
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Booking series updated successfully", "bookings": bookingsResponse(updated)})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+	case errors.Is(err, errSeriesConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "部分日期无法改到新的时间段，系列预约未修改", "conflicts": conflicts})
+	case errors.Is(err, errSeriesInvalidSlots):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking series"})
+	}
+}
+
+// CancelBookingSeriesHandler 取消系列中 from 之后仍有效的全部预约，释放的时间段自动分配给候补；
+// 只取消其中一次时使用 POST /api/bookings/:id/cancel
+func CancelBookingSeriesHandler(c *gin.Context) {
+	seriesID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req CancelBookingSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cancellation reason is required"})
+		return
+	}
+	from, err := parseSeriesFrom(req.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var cancelled []models.Booking
+	var promoted []models.WaitlistEntry
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&models.BookingSeries{}, seriesID).Error; err != nil {
+			return err
+		}
+		bookings, err := lockSeriesBookings(tx, seriesID, from)
+		if err != nil {
+			return err
+		}
+		for _, booking := range bookings {
+			if err := applyBookingStatus(tx, &booking, models.BookingStatusCancelled, req.Reason, userID); err != nil {
+				return err
+			}
+			entries, err := promoteWaitlist(tx, booking.CoachID, booking.BookingDate, userID)
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, entries...)
+			cancelled = append(cancelled, booking)
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "Booking series cancelled successfully",
+			"bookings":          bookingsResponse(cancelled),
+			"promoted_waitlist": waitlistsResponse(promoted),
+		})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking series not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking series"})
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errInvalidTransition = errors.New("invalid booking status transition")
+	errLessonNotStarted  = errors.New("lesson has not taken place yet")
+)
+
+// CancelBookingRequest 定义了取消预约的请求结构
+type CancelBookingRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// applyBookingStatus 将预约变更为新状态并写入状态变更历史，取消时同时取消团体课报名；调用方需保证已校验状态机
+func applyBookingStatus(tx *gorm.DB, booking *models.Booking, to, reason string, changedBy uint) error {
+	now := time.Now()
+	change := models.BookingStatusChange{
+		BookingID:  booking.ID,
+		FromStatus: booking.Status,
+		ToStatus:   to,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+	}
+	if err := tx.Model(booking).Updates(map[string]interface{}{
+		"status":            to,
+		"status_reason":     reason,
+		"status_changed_at": now,
+		"sequence":          gorm.Expr("sequence + 1"),
+	}).Error; err != nil {
+		return err
+	}
+	// 团体课的预约取消后，其中的报名一并取消
+	if to == models.BookingStatusCancelled {
+		if err := tx.Model(&models.GroupEnrollment{}).
+			Where("status = ? AND group_session_id IN (?)", models.EnrollmentStatusEnrolled,
+				tx.Model(&models.GroupSession{}).Select("id").Where("booking_id = ?", booking.ID)).
+			Updates(map[string]interface{}{
+				"status":       models.EnrollmentStatusCancelled,
+				"cancelled_at": now,
+			}).Error; err != nil {
+			return err
+		}
+	}
+	booking.Status = to
+	booking.StatusReason = reason
+	booking.StatusChangedAt = &now
+	booking.Sequence++
+	return tx.Create(&change).Error
+}
+
+// changeBookingStatus 在事务中锁定预约，按状态机变更状态；取消时返回自动转为预约的候补
+func changeBookingStatus(bookingID string, to, reason string, userID uint) (models.Booking, []models.WaitlistEntry, error) {
+	var booking models.Booking
+	var promoted []models.WaitlistEntry
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 取消会为候补写入新预约，先按创建预约的顺序锁定教练当天，再锁定预约本身，避免死锁
+		if to == models.BookingStatusCancelled {
+			var day models.Booking
+			if err := tx.Select("id", "coach_id", "booking_date").First(&day, bookingID).Error; err != nil {
+				return err
+			}
+			if err := lockCoachDay(tx, day.CoachID, day.BookingDate); err != nil {
+				return err
+			}
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Slots").Preload("Course").First(&booking, bookingID).Error; err != nil {
+			return err
+		}
+		if !models.CanTransitionBooking(booking.Status, to) {
+			return errInvalidTransition
+		}
+		// 完成或未到场只能在课程当天及之后标记
+		if (to == models.BookingStatusCompleted || to == models.BookingStatusNoShow) && booking.BookingDate.After(time.Now()) {
+			return errLessonNotStarted
+		}
+		if err := applyBookingStatus(tx, &booking, to, reason, userID); err != nil {
+			return err
+		}
+		// 取消后释放的时间段自动分配给候补
+		if to == models.BookingStatusCancelled {
+			var err error
+			promoted, err = promoteWaitlist(tx, booking.CoachID, booking.BookingDate, userID)
+			return err
+		}
+		return nil
+	})
+	return booking, promoted, err
+}
+
+// respondBookingStatusError 将状态变更错误转换为对应的HTTP响应
+func respondBookingStatusError(c *gin.Context, err error, booking models.Booking, to string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+	case errors.Is(err, errInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot change booking status from " + booking.Status + " to " + to})
+	case errors.Is(err, errLessonNotStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": "课程尚未开始，不能标记为" + to})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+	}
+}
+
+// transitionBooking 变更路径参数 id 指定预约的状态并返回结果
+func transitionBooking(c *gin.Context, to, reason string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	booking, promoted, err := changeBookingStatus(c.Param("id"), to, reason, userID)
+	if err != nil {
+		respondBookingStatusError(c, err, booking, to)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Booking " + to + " successfully",
+		"booking":           bookingResponse(booking),
+		"promoted_waitlist": waitlistsResponse(promoted),
+	})
+}
+
+// ConfirmBookingHandler 确认待确认的预约
+func ConfirmBookingHandler(c *gin.Context) {
+	transitionBooking(c, models.BookingStatusConfirmed, "")
+}
+
+// CancelBookingHandler 取消预约，需要提供取消原因
+func CancelBookingHandler(c *gin.Context) {
+	var req CancelBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cancellation reason is required"})
+		return
+	}
+	transitionBooking(c, models.BookingStatusCancelled, req.Reason)
+}
+
+// CompleteBookingHandler 将预约标记为已完成
+func CompleteBookingHandler(c *gin.Context) {
+	transitionBooking(c, models.BookingStatusCompleted, "")
+}
+
+// NoShowBookingHandler 将预约标记为学员未到场
+func NoShowBookingHandler(c *gin.Context) {
+	transitionBooking(c, models.BookingStatusNoShow, "")
+}
+
+// GetBookingHistoryHandler 查看预约的状态变更历史
+func GetBookingHistoryHandler(c *gin.Context) {
+	var changes []models.BookingStatusChange
+	if err := database.DB.Where("booking_id = ?", c.Param("id")).Order("created_at, id").Find(&changes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve booking history"})
+		return
+	}
+	resp := make([]gin.H, 0, len(changes))
+	for _, ch := range changes {
+		resp = append(resp, gin.H{
+			"from_status": ch.FromStatus,
+			"to_status":   ch.ToStatus,
+			"reason":      ch.Reason,
+			"changed_by":  ch.ChangedBy,
+			"changed_at":  ch.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
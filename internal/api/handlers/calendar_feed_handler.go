@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/ical"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// calendarBaseURL 返回订阅链接的地址前缀，未配置时根据请求推断
+func calendarBaseURL(c *gin.Context) string {
+	if base := config.Cfg.Calendar.BaseURL; base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// calendarFeedResponse 返回订阅链接，令牌只在生成时返回一次
+func calendarFeedResponse(c *gin.Context, token string) gin.H {
+	url := calendarBaseURL(c) + "/api/calendar/feeds/" + token + ".ics"
+	webcal := "webcal://" + strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	return gin.H{
+		"url":        url,
+		"webcal_url": webcal,
+	}
+}
+
+// issueCalendarFeed 为教练（coachID 为空时为全校）生成新的订阅令牌，旧令牌立即失效
+func issueCalendarFeed(coachID *uint, createdBy uint) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := revokeCalendarFeed(tx, coachID); err != nil {
+			return err
+		}
+		return tx.Create(&models.CalendarFeed{CoachID: coachID, TokenHash: hashToken(token), CreatedBy: createdBy}).Error
+	})
+	return token, err
+}
+
+func revokeCalendarFeed(tx *gorm.DB, coachID *uint) error {
+	if coachID == nil {
+		return tx.Where("coach_id IS NULL").Delete(&models.CalendarFeed{}).Error
+	}
+	return tx.Where("coach_id = ?", *coachID).Delete(&models.CalendarFeed{}).Error
+}
+
+// bookingEvents 把预约转换为日历事件，不连续的时间段分别生成事件；coachNames 不为空时在标题中注明教练
+func bookingEvents(bookings []models.Booking, coachNames map[uint]string) []ical.Event {
+	var events []ical.Event
+	for _, b := range bookings {
+		summary := b.ClientInfo
+		if summary == "" {
+			summary = "课程"
+		}
+		description := "学员：" + b.ClientInfo
+		if b.Course != nil {
+			summary += " · " + b.Course.Name
+			description += "\n课程：" + b.Course.Name
+		}
+		if coachNames != nil {
+			summary = coachNames[b.CoachID] + "：" + summary
+			description = "教练：" + coachNames[b.CoachID] + "\n" + description
+		}
+		description += "\n时间：" + b.TimeSlot
+
+		status := ical.StatusConfirmed
+		switch b.Status {
+		case models.BookingStatusPending:
+			status = ical.StatusTentative
+		case models.BookingStatusCancelled:
+			status = ical.StatusCancelled
+			if b.StatusReason != "" {
+				description += "\n取消原因：" + b.StatusReason
+			}
+		}
+		// 旧数据没有 updated_at，退回到创建或状态变更时间
+		modified := b.UpdatedAt
+		if modified.IsZero() {
+			modified = b.CreatedAt
+			if b.StatusChangedAt != nil {
+				modified = *b.StatusChangedAt
+			}
+		}
+
+		day := time.Date(b.BookingDate.Year(), b.BookingDate.Month(), b.BookingDate.Day(), 0, 0, 0, 0, time.Local)
+		for _, s := range schedule.Merge(toScheduleSlots(b.Slots)) {
+			start, err := schedule.ParseClock(s.Start)
+			if err != nil {
+				continue
+			}
+			end, err := schedule.ParseClock(s.End)
+			if err != nil {
+				continue
+			}
+			events = append(events, ical.Event{
+				UID:          fmt.Sprintf("booking-%d-%s@classorder", b.ID, strings.ReplaceAll(s.Start, ":", "")),
+				Start:        day.Add(time.Duration(start) * time.Minute),
+				End:          day.Add(time.Duration(end) * time.Minute),
+				Summary:      summary,
+				Description:  description,
+				Status:       status,
+				LastModified: modified,
+				Sequence:     b.Sequence,
+			})
+		}
+	}
+	return events
+}
+
+// CalendarFeedHandler 输出订阅令牌对应的 iCalendar 日历，无需登录；链接中的 .ics 后缀可省略。
+// 教练账号被停用时其订阅链接返回 404
+func CalendarFeedHandler(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	var feed models.CalendarFeed
+	if token == "" || database.DB.Where("token_hash = ?", hashToken(token)).First(&feed).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	pastDays := config.Cfg.Calendar.PastDays
+	if pastDays <= 0 {
+		pastDays = 30
+	}
+	since := time.Now().AddDate(0, 0, -pastDays).Format("2006-01-02")
+	db := database.DB.Preload("Slots").Preload("Course").Where("booking_date >= ?", since)
+	name := "全部课程"
+	var coachNames map[uint]string
+	if feed.CoachID != nil {
+		var coach models.Coach
+		// 教练账号停用后订阅链接随之失效
+		if err := database.DB.Preload("User").First(&coach, *feed.CoachID).Error; err != nil || coach.User.Disabled {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+			return
+		}
+		name = coach.Name + "的课程"
+		db = db.Where("coach_id = ?", coach.ID)
+	} else {
+		var coaches []models.Coach
+		if err := database.DB.Select("id", "name").Find(&coaches).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+			return
+		}
+		coachNames = make(map[uint]string, len(coaches))
+		for _, co := range coaches {
+			coachNames[co.ID] = co.Name
+		}
+	}
+	var bookings []models.Booking
+	if err := db.Order("booking_date, id").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="classorder.ics"`)
+	c.Status(http.StatusOK)
+	_ = ical.Write(c.Writer, ical.Calendar{Name: name, Events: bookingEvents(bookings, coachNames)})
+}
+
+// RegenerateOwnCalendarTokenHandler 教练生成自己的日历订阅链接，旧链接立即失效
+func RegenerateOwnCalendarTokenHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	token, err := issueCalendarFeed(&coach.ID, coach.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar link"})
+		return
+	}
+	c.JSON(http.StatusOK, calendarFeedResponse(c, token))
+}
+
+// RevokeOwnCalendarTokenHandler 教练停用自己的日历订阅链接
+func RevokeOwnCalendarTokenHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	if err := revokeCalendarFeed(database.DB, &coach.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar link revoked"})
+}
+
+// RegenerateSchoolCalendarTokenHandler 生成包含全部教练课程的全校订阅链接，旧链接立即失效
+func RegenerateSchoolCalendarTokenHandler(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	token, err := issueCalendarFeed(nil, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar link"})
+		return
+	}
+	c.JSON(http.StatusOK, calendarFeedResponse(c, token))
+}
+
+// RevokeSchoolCalendarTokenHandler 停用全校订阅链接
+func RevokeSchoolCalendarTokenHandler(c *gin.Context) {
+	if err := revokeCalendarFeed(database.DB, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke calendar link"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar link revoked"})
+}
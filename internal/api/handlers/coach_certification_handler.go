@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CertificationInput 定义了一张教练证书
+type CertificationInput struct {
+	Body       string `json:"body" binding:"required"` // 颁证机构，例如 PSIA、CASI
+	Level      string `json:"level"`
+	Discipline string `json:"discipline"` // ski 或 snowboard，可为空
+	ExpiresAt  string `json:"expires_at"` // YYYY-MM-DD，为空表示长期有效
+}
+
+// SetCertificationsRequest 定义了设置教练证书的请求结构，会整体替换原有证书
+type SetCertificationsRequest struct {
+	Certifications []CertificationInput `json:"certifications" binding:"dive"`
+}
+
+// SetSpecialtiesRequest 定义了设置教练专长标签的请求结构，会整体替换原有标签
+type SetSpecialtiesRequest struct {
+	Specialties []string `json:"specialties"`
+}
+
+// seasonEnd 返回当前雪季的结束日期，配置为 MM-DD，今年的日期已过时取明年
+func seasonEnd(now time.Time) time.Time {
+	raw := config.Cfg.Schedule.SeasonEnd
+	if raw == "" {
+		raw = "04-30"
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	md, err := time.Parse("01-02", raw)
+	if err != nil {
+		md, _ = time.Parse("01-02", "04-30")
+	}
+	end := time.Date(now.Year(), md.Month(), md.Day(), 0, 0, 0, 0, time.UTC)
+	if end.Before(today) {
+		end = end.AddDate(1, 0, 0)
+	}
+	return end
+}
+
+func certificationResponse(cert models.CoachCertification, now time.Time) gin.H {
+	var expiresAt string
+	expired, expiring := false, false
+	if cert.ExpiresAt != nil {
+		expiresAt = cert.ExpiresAt.Format("2006-01-02")
+		today := now.Format("2006-01-02")
+		expired = expiresAt < today
+		expiring = !expired && expiresAt <= seasonEnd(now).Format("2006-01-02")
+	}
+	return gin.H{
+		"id":         cert.ID,
+		"body":       cert.Body,
+		"level":      cert.Level,
+		"discipline": cert.Discipline,
+		"expires_at": expiresAt,
+		"expired":    expired,
+		"expiring":   expiring, // 本雪季内到期
+	}
+}
+
+// coachResponse 返回教练的公开信息，包括证书、专长标签和授课语言
+func coachResponse(coach models.Coach) gin.H {
+	now := time.Now()
+	certs := make([]gin.H, 0, len(coach.Certifications))
+	for _, cert := range coach.Certifications {
+		certs = append(certs, certificationResponse(cert, now))
+	}
+	tags := make([]string, 0, len(coach.Specialties))
+	for _, s := range coach.Specialties {
+		tags = append(tags, s.Tag)
+	}
+	langs := make([]string, 0, len(coach.Languages))
+	for _, l := range coach.Languages {
+		langs = append(langs, l.Language)
+	}
+	return gin.H{
+		"id":             coach.ID,
+		"user_id":        coach.UserID,
+		"username":       coach.User.Username,
+		"name":           coach.Name,
+		"description":    coach.Description,
+		"avatar_url":     coach.AvatarURL,
+		"discipline":     coach.Discipline,
+		"certifications": certs,
+		"specialties":    tags,
+		"languages":      langs,
+	}
+}
+
+// validateDiscipline 校验项目，空字符串表示未指定
+func validateDiscipline(d string) error {
+	if d != "" && !models.IsValidDiscipline(d) {
+		return errors.New("invalid discipline: " + d)
+	}
+	return nil
+}
+
+// buildCertifications 校验请求中的证书并转换为模型
+func buildCertifications(coachID uint, inputs []CertificationInput) ([]models.CoachCertification, error) {
+	certs := make([]models.CoachCertification, 0, len(inputs))
+	for _, in := range inputs {
+		cert := models.CoachCertification{
+			CoachID:    coachID,
+			Body:       strings.TrimSpace(in.Body),
+			Level:      strings.TrimSpace(in.Level),
+			Discipline: in.Discipline,
+		}
+		if cert.Body == "" {
+			return nil, errors.New("certification body is required")
+		}
+		if err := validateDiscipline(cert.Discipline); err != nil {
+			return nil, err
+		}
+		if in.ExpiresAt != "" {
+			d, err := time.Parse("2006-01-02", in.ExpiresAt)
+			if err != nil {
+				return nil, errors.New("invalid expires_at: " + in.ExpiresAt)
+			}
+			cert.ExpiresAt = &d
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// normalizeTags 规范化专长标签和授课语言：去掉空白、转为小写并去重
+func normalizeTags(tags []string, maxLen int) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		if len([]rune(t)) > maxLen {
+			return nil, errors.New("value is too long: " + t)
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// replaceSpecialties 整体替换教练的专长标签
+func replaceSpecialties(tx *gorm.DB, coachID uint, tags []string) error {
+	if err := tx.Where("coach_id = ?", coachID).Delete(&models.CoachSpecialty{}).Error; err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if err := tx.Create(&models.CoachSpecialty{CoachID: coachID, Tag: t}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceLanguages 整体替换教练的授课语言
+func replaceLanguages(tx *gorm.DB, coachID uint, langs []string) error {
+	if err := tx.Where("coach_id = ?", coachID).Delete(&models.CoachLanguage{}).Error; err != nil {
+		return err
+	}
+	for _, l := range langs {
+		if err := tx.Create(&models.CoachLanguage{CoachID: coachID, Language: l}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCoachResponse 重新加载教练及其证书、专长并写入响应
+func loadCoachResponse(c *gin.Context, coachID uint) {
+	var coach models.Coach
+	if err := database.DB.Preload("User").Preload("Certifications").Preload("Specialties").Preload("Languages").First(&coach, coachID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coach"})
+		return
+	}
+	c.JSON(http.StatusOK, coachResponse(coach))
+}
+
+// SetCoachCertificationsHandler 管理员设置教练的资格证书
+func SetCoachCertificationsHandler(c *gin.Context) {
+	coachID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := database.DB.First(&models.Coach{}, coachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+	var req SetCertificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	certs, err := buildCertifications(coachID, req.Certifications)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coach_id = ?", coachID).Delete(&models.CoachCertification{}).Error; err != nil {
+			return err
+		}
+		if len(certs) == 0 {
+			return nil
+		}
+		return tx.Create(&certs).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save certifications"})
+		return
+	}
+	loadCoachResponse(c, coachID)
+}
+
+// SetCoachSpecialtiesHandler 管理员设置教练的专长标签
+func SetCoachSpecialtiesHandler(c *gin.Context) {
+	coachID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := database.DB.First(&models.Coach{}, coachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+	saveSpecialties(c, coachID)
+}
+
+// SetOwnSpecialtiesHandler 教练设置自己的专长标签
+func SetOwnSpecialtiesHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	saveSpecialties(c, coach.ID)
+}
+
+func saveSpecialties(c *gin.Context, coachID uint) {
+	var req SetSpecialtiesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	tags, err := normalizeTags(req.Specialties, 50)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceSpecialties(tx, coachID, tags)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save specialties"})
+		return
+	}
+	loadCoachResponse(c, coachID)
+}
+
+// ListExpiringCertificationsHandler 列出已过期或在本雪季结束前到期的教练证书，
+// before=YYYY-MM-DD 可指定其他截止日期
+func ListExpiringCertificationsHandler(c *gin.Context) {
+	now := time.Now()
+	before := seasonEnd(now)
+	if s := c.Query("before"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before date format"})
+			return
+		}
+		before = d
+	}
+	var certs []models.CoachCertification
+	if err := database.DB.Where("expires_at IS NOT NULL AND expires_at <= ?", before.Format("2006-01-02")).
+		Order("expires_at, coach_id").Find(&certs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve certifications"})
+		return
+	}
+	coachIDs := make([]uint, 0, len(certs))
+	for _, cert := range certs {
+		coachIDs = append(coachIDs, cert.CoachID)
+	}
+	var coaches []models.Coach
+	if len(coachIDs) > 0 {
+		if err := database.DB.Select("id", "name").Where("id IN ?", coachIDs).Find(&coaches).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve certifications"})
+			return
+		}
+	}
+	names := make(map[uint]string, len(coaches))
+	for _, co := range coaches {
+		names[co.ID] = co.Name
+	}
+	resp := make([]gin.H, 0, len(certs))
+	for _, cert := range certs {
+		item := certificationResponse(cert, now)
+		item["coach_id"] = cert.CoachID
+		item["coach_name"] = names[cert.CoachID]
+		resp = append(resp, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"before": before.Format("2006-01-02"), "certifications": resp})
+}
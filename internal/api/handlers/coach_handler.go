@@ -4,6 +4,8 @@ import (
 	"classOrder-backend/internal/database"
 	"classOrder-backend/internal/models"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -12,11 +14,13 @@ import (
 
 // CreateCoachRequest 定义了创建教练的请求结构
 type CreateCoachRequest struct {
-	Username    string `json:"username" binding:"required"`
-	Password    string `json:"password" binding:"required"`
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	AvatarURL   string `json:"avatar_url"`
+	Username    string   `json:"username" binding:"required"`
+	Password    string   `json:"password" binding:"required"`
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	AvatarURL   string   `json:"avatar_url"`
+	Discipline  string   `json:"discipline"` // ski、snowboard 或 both
+	Languages   []string `json:"languages"`  // 授课语言，例如 zh、en
 }
 
 // CreateCoachHandler 创建一个新的教练及其关联的用户账户
@@ -26,6 +30,15 @@ func CreateCoachHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
 		return
 	}
+	if err := validateDiscipline(req.Discipline); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	languages, err := normalizeTags(req.Languages, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 哈希密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -40,7 +53,7 @@ func CreateCoachHandler(c *gin.Context) {
 		newUser := models.User{
 			Username:     req.Username,
 			PasswordHash: string(hashedPassword),
-			Role:         "coach",
+			Role:         models.RoleCoach,
 		}
 		if err := tx.Create(&newUser).Error; err != nil {
 			return err // 返回错误以回滚事务
@@ -52,10 +65,14 @@ func CreateCoachHandler(c *gin.Context) {
 			Name:        req.Name,
 			Description: req.Description,
 			AvatarURL:   req.AvatarURL,
+			Discipline:  req.Discipline,
 		}
 		if err := tx.Create(&newCoach).Error; err != nil {
 			return err // 返回错误以回滚事务
 		}
+		if err := replaceLanguages(tx, newCoach.ID, languages); err != nil {
+			return err
+		}
 
 		// 事务成功，自动提交
 		return nil
@@ -69,37 +86,103 @@ func CreateCoachHandler(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"message": "Coach created successfully"})
 }
 
-// ListCoachesHandler 获取所有教练的列表
+// ListCoachesHandler 获取教练列表
+// 支持搜索和筛选：q（姓名或简介）、discipline（项目，双板/单板均可教的教练也会匹配）、
+// cert（持有未过期的该机构证书）、specialty（专长标签）、language（授课语言）、available_on（当天还有空闲时间）；
+// sort 可选 name、newest、rating（按平均分从高到低）；page/page_size 分页，总数通过 X-Total-Count 返回
 func ListCoachesHandler(c *gin.Context) {
+	page, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+	query := database.DB.Model(&models.Coach{})
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("coaches.name LIKE ? OR coaches.description LIKE ?", like, like)
+	}
+	if d := c.Query("discipline"); d != "" {
+		if !models.IsValidDiscipline(d) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid discipline"})
+			return
+		}
+		query = query.Where("coaches.discipline IN ?", []string{d, models.DisciplineBoth})
+	}
+	if cert := c.Query("cert"); cert != "" {
+		today := time.Now().Format("2006-01-02")
+		query = query.Where("coaches.id IN (?)", database.DB.Model(&models.CoachCertification{}).Select("coach_id").
+			Where("body = ? AND (expires_at IS NULL OR expires_at >= ?)", cert, today))
+	}
+	if tag := c.Query("specialty"); tag != "" {
+		query = query.Where("coaches.id IN (?)", database.DB.Model(&models.CoachSpecialty{}).Select("coach_id").
+			Where("tag = ?", strings.ToLower(strings.TrimSpace(tag))))
+	}
+	if lang := c.Query("language"); lang != "" {
+		query = query.Where("coaches.id IN (?)", database.DB.Model(&models.CoachLanguage{}).Select("coach_id").
+			Where("language = ?", strings.ToLower(strings.TrimSpace(lang))))
+	}
+	if s := c.Query("available_on"); s != "" {
+		date, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid available_on date format"})
+			return
+		}
+		// 空闲时间需要结合工作时段、休假和已有预约计算，先按其他条件缩小范围
+		var ids []uint
+		if err := query.Session(&gorm.Session{}).Pluck("coaches.id", &ids).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+			return
+		}
+		free, err := coachesFreeOn(ids, date)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+			return
+		}
+		query = query.Where("coaches.id IN ?", free)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+		return
+	}
+	switch c.Query("sort") {
+	case "":
+		query = query.Order("coaches.id")
+	case "name":
+		query = query.Order("coaches.name, coaches.id")
+	case "newest":
+		query = query.Order("coaches.created_at DESC, coaches.id DESC")
+	case "rating":
+		query = query.Joins(ratingOrderJoin).
+			Order("ratings.avg_rating IS NULL, ratings.avg_rating DESC, ratings.review_count DESC, coaches.id")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort"})
+		return
+	}
 	var coaches []models.Coach
-	// Preload("User") 会同时加载关联的User信息
-	if err := database.DB.Preload("User").Find(&coaches).Error; err != nil {
+	if err := page.apply(query.Preload("User").Preload("Certifications").Preload("Specialties").Preload("Languages")).
+		Find(&coaches).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
 		return
 	}
-
-	// 为了安全，我们不应该返回密码哈希值。可以定义一个新的struct来返回安全的数据。
-	type SafeCoachResponse struct {
-		ID          uint   `json:"id"`
-		UserID      uint   `json:"user_id"`
-		Username    string `json:"username"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		AvatarURL   string `json:"avatar_url"`
+	ids := make([]uint, 0, len(coaches))
+	for _, coach := range coaches {
+		ids = append(ids, coach.ID)
+	}
+	ratings, err := coachRatings(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coaches"})
+		return
 	}
 
-	var response []SafeCoachResponse
+	// 为了安全，不返回密码哈希值
+	response := make([]gin.H, 0, len(coaches))
 	for _, coach := range coaches {
-		response = append(response, SafeCoachResponse{
-			ID:          coach.ID,
-			UserID:      coach.UserID,
-			Username:    coach.User.Username,
-			Name:        coach.Name,
-			Description: coach.Description,
-			AvatarURL:   coach.AvatarURL,
-		})
+		response = append(response, withRating(coachResponse(coach), ratings[coach.ID]))
 	}
 
+	setPageHeaders(c, page, total)
 	c.JSON(http.StatusOK, response)
 }
 
@@ -107,7 +190,7 @@ func ListCoachesHandler(c *gin.Context) {
 func GetCoachHandler(c *gin.Context) {
 	id := c.Param("id")
 	var coach models.Coach
-	if err := database.DB.Preload("User").First(&coach, id).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("Certifications").Preload("Specialties").Preload("Languages").First(&coach, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
 		} else {
@@ -116,27 +199,26 @@ func GetCoachHandler(c *gin.Context) {
 		return
 	}
 
-	// 同样，返回安全的数据
-	response := gin.H{
-		"id":          coach.ID,
-		"user_id":     coach.UserID,
-		"username":    coach.User.Username,
-		"name":        coach.Name,
-		"description": coach.Description,
-		"avatar_url":  coach.AvatarURL,
+	ratings, err := coachRatings([]uint{coach.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coach"})
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	// 同样，返回安全的数据
+	c.JSON(http.StatusOK, withRating(coachResponse(coach), ratings[coach.ID]))
 }
 
 // UpdateCoachRequest 定义了更新教练的请求结构
 // 新增 Password 字段
 type UpdateCoachRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	AvatarURL   string `json:"avatar_url"`
-	Password    string `json:"password"`
-	OldPassword string `json:"old_password"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	AvatarURL   string   `json:"avatar_url"`
+	Discipline  string   `json:"discipline"` // 为空表示不修改
+	Languages   []string `json:"languages"`  // 不传表示不修改，传空数组表示清空
+	Password    string   `json:"password"`
+	OldPassword string   `json:"old_password"`
 }
 
 // UpdateCoachHandler 更新教练信息
@@ -154,12 +236,32 @@ func UpdateCoachHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 		return
 	}
+	if err := validateDiscipline(req.Discipline); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	languages, err := normalizeTags(req.Languages, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 更新教练表
 	coach.Name = req.Name
 	coach.Description = req.Description
 	coach.AvatarURL = req.AvatarURL
-	if err := database.DB.Save(&coach).Error; err != nil {
+	if req.Discipline != "" {
+		coach.Discipline = req.Discipline
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&coach).Error; err != nil {
+			return err
+		}
+		if req.Languages == nil {
+			return nil
+		}
+		return replaceLanguages(tx, coach.ID, languages)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coach"})
 		return
 	}
@@ -188,6 +290,10 @@ func DeleteCoachHandler(c *gin.Context) {
 
 	// 使用事务确保原子性
 	dbErr := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 先撤销该教练的所有会话，已签发的令牌立即失效
+		if err := revokeUserSessions(tx, coach.UserID); err != nil {
+			return err
+		}
 		// 删除Coach记录会导致User记录被级联删除（如果DB支持或GORM处理得当）
 		// 但为了保险，我们显式删除User
 		if err := tx.Delete(&models.User{}, coach.UserID).Error; err != nil {
@@ -211,52 +317,80 @@ func DeleteCoachHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Coach deleted successfully"})
 }
 
-// 新增：教练自助获取个人信息
-func GetOwnCoachProfileHandler(c *gin.Context) {
+// currentUserID 从JWT中取出当前登录用户的ID，失败时直接写入401响应
+func currentUserID(c *gin.Context) (uint, bool) {
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return
+		return 0, false
 	}
 	userID, ok := userIDVal.(float64) // JWT 默认 float64
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID type"})
-		return
+		return 0, false
 	}
+	return uint(userID), true
+}
 
+// currentCoach 查找当前登录用户对应的教练记录，失败时直接写入错误响应
+func currentCoach(c *gin.Context) (models.Coach, bool) {
 	var coach models.Coach
-	if err := database.DB.Preload("User").Where("user_id = ?", uint(userID)).First(&coach).Error; err != nil {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return coach, false
+	}
+	if err := database.DB.Preload("User").Where("user_id = ?", userID).First(&coach).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
-		return
+		return coach, false
 	}
+	return coach, true
+}
 
-	response := gin.H{
-		"id":          coach.ID,
-		"user_id":     coach.UserID,
-		"username":    coach.User.Username,
-		"name":        coach.Name,
-		"description": coach.Description,
-		"avatar_url":  coach.AvatarURL,
+// scopedCoachID 返回 CoachScopeMiddleware 限定的教练ID，不受教练范围限制时返回 false
+func scopedCoachID(c *gin.Context) (uint, bool) {
+	v, exists := c.Get("coach_id")
+	if !exists {
+		return 0, false
 	}
-	c.JSON(http.StatusOK, response)
+	id, ok := v.(uint)
+	return id, ok
 }
 
-// 新增：教练自助修改个人信息
-func UpdateOwnCoachProfileHandler(c *gin.Context) {
-	userIDVal, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
-		return
+// requireOwnCoach 教练只能为自己创建或调整数据，coachID 属于其他教练时写入 403 响应
+func requireOwnCoach(c *gin.Context, coachID uint) bool {
+	if scoped, ok := scopedCoachID(c); ok && scoped != coachID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Coaches can only manage their own bookings."})
+		return false
 	}
-	userID, ok := userIDVal.(float64)
+	return true
+}
+
+// 新增：教练自助获取个人信息
+func GetOwnCoachProfileHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID type"})
 		return
 	}
 
-	var coach models.Coach
-	if err := database.DB.Preload("User").Where("user_id = ?", uint(userID)).First(&coach).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+	if err := database.DB.Preload("Certifications").Preload("Specialties").Preload("Languages").First(&coach, coach.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coach"})
+		return
+	}
+	var feeds int64
+	if err := database.DB.Model(&models.CalendarFeed{}).Where("coach_id = ?", coach.ID).Count(&feeds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve coach"})
+		return
+	}
+	response := coachResponse(coach)
+	// 订阅链接只在生成时返回，这里只说明是否已启用
+	response["calendar_feed_enabled"] = feeds > 0
+	c.JSON(http.StatusOK, response)
+}
+
+// 新增：教练自助修改个人信息
+func UpdateOwnCoachProfileHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
 		return
 	}
 
@@ -267,6 +401,15 @@ func UpdateOwnCoachProfileHandler(c *gin.Context) {
 		return
 	}
 	_ = c.ShouldBindJSON(&body)
+	if err := validateDiscipline(req.Discipline); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	languages, err := normalizeTags(req.Languages, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if req.Name != "" {
 		coach.Name = req.Name
@@ -277,7 +420,18 @@ func UpdateOwnCoachProfileHandler(c *gin.Context) {
 	if req.AvatarURL != "" {
 		coach.AvatarURL = req.AvatarURL
 	}
-	if err := database.DB.Save(&coach).Error; err != nil {
+	if req.Discipline != "" {
+		coach.Discipline = req.Discipline
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&coach).Error; err != nil {
+			return err
+		}
+		if req.Languages == nil {
+			return nil
+		}
+		return replaceLanguages(tx, coach.ID, languages)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coach"})
 		return
 	}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CourseRequest 定义了创建/更新课程的请求结构
+type CourseRequest struct {
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	Price           int    `json:"price" binding:"min=0"`
+	DurationMinutes int    `json:"duration_minutes" binding:"min=0"`
+	Active          *bool  `json:"active"`
+}
+
+func courseResponse(course models.Course) gin.H {
+	return gin.H{
+		"id":               course.ID,
+		"name":             course.Name,
+		"description":      course.Description,
+		"price":            course.Price,
+		"duration_minutes": course.DurationMinutes,
+		"active":           course.Active,
+	}
+}
+
+func coursesResponse(courses []models.Course) []gin.H {
+	resp := make([]gin.H, 0, len(courses))
+	for _, course := range courses {
+		resp = append(resp, courseResponse(course))
+	}
+	return resp
+}
+
+// loadBookableCourse 查找可用于预约的在售课程
+func loadBookableCourse(courseID uint) (*models.Course, error) {
+	var course models.Course
+	if err := database.DB.Where("id = ? AND active = ?", courseID, true).First(&course).Error; err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// ListCoursesHandler 获取在售课程列表 (公开)
+func ListCoursesHandler(c *gin.Context) {
+	var courses []models.Course
+	if err := database.DB.Where("active = ?", true).Order("id").Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve courses"})
+		return
+	}
+	c.JSON(http.StatusOK, coursesResponse(courses))
+}
+
+// ListAllCoursesHandler 管理员获取包括已下架课程在内的全部课程
+func ListAllCoursesHandler(c *gin.Context) {
+	var courses []models.Course
+	if err := database.DB.Order("id").Find(&courses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve courses"})
+		return
+	}
+	c.JSON(http.StatusOK, coursesResponse(courses))
+}
+
+// GetCourseHandler 获取单个课程信息 (公开)
+func GetCourseHandler(c *gin.Context) {
+	var course models.Course
+	if err := database.DB.First(&course, c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve course"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, courseResponse(course))
+}
+
+// CreateCourseHandler 创建课程
+func CreateCourseHandler(c *gin.Context) {
+	var req CourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	course := models.Course{
+		Name:            req.Name,
+		Description:     req.Description,
+		Price:           req.Price,
+		DurationMinutes: req.DurationMinutes,
+		Active:          req.Active == nil || *req.Active,
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&course).Error; err != nil {
+			return err
+		}
+		// active 的零值会被数据库默认值覆盖，需要单独写入
+		if !course.Active {
+			return tx.Model(&course).Update("active", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create course"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Course created successfully", "course": courseResponse(course)})
+}
+
+// UpdateCourseHandler 更新课程信息，已有预约保留预约时的价格
+func UpdateCourseHandler(c *gin.Context) {
+	var course models.Course
+	if err := database.DB.First(&course, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+	var req CourseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	course.Name = req.Name
+	course.Description = req.Description
+	course.Price = req.Price
+	course.DurationMinutes = req.DurationMinutes
+	if req.Active != nil {
+		course.Active = *req.Active
+	}
+	if err := database.DB.Save(&course).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update course"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Course updated successfully", "course": courseResponse(course)})
+}
+
+// DeleteCourseHandler 删除课程；已被预约（含团体课占用的预约）或候补引用的课程只会下架，以保留相关记录
+func DeleteCourseHandler(c *gin.Context) {
+	var course models.Course
+	if err := database.DB.First(&course, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Course not found"})
+		return
+	}
+	var references int64
+	for _, model := range []interface{}{&models.Booking{}, &models.WaitlistEntry{}} {
+		var count int64
+		if err := database.DB.Model(model).Where("course_id = ?", course.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete course"})
+			return
+		}
+		references += count
+	}
+	if references > 0 {
+		if err := database.DB.Model(&course).Update("active", false).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deactivate course"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Course is referenced by bookings or waitlist entries and has been deactivated"})
+		return
+	}
+	if err := database.DB.Delete(&course).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete course"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Course deleted successfully"})
+}
@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errSessionFull       = errors.New("group session is full")
+	errSessionClosed     = errors.New("group session is not open for enrollment")
+	errAlreadyCancelled  = errors.New("enrollment already cancelled")
+	errCapacityTooSmall  = errors.New("capacity is smaller than current enrollments")
+	errEnrollmentMissing = errors.New("enrollment not found")
+)
+
+// CreateGroupSessionRequest 定义了创建团体课的请求结构，时间段字段与创建预约一致
+type CreateGroupSessionRequest struct {
+	Title     string          `json:"title" binding:"required"`
+	Capacity  int             `json:"capacity" binding:"required,min=1"`
+	CoachID   uint            `json:"coach_id" binding:"required"`
+	Date      string          `json:"date" binding:"required"` // YYYY-MM-DD
+	Slots     []schedule.Slot `json:"slots"`
+	TimeSlots string          `json:"time_slots"`
+	CourseID  *uint           `json:"course_id"`
+	StartTime string          `json:"start_time"`
+}
+
+// UpdateGroupSessionRequest 定义了修改团体课的请求结构
+type UpdateGroupSessionRequest struct {
+	Title    string `json:"title"`
+	Capacity int    `json:"capacity" binding:"min=0"`
+}
+
+// EnrollRequest 定义了报名团体课的请求结构
+type EnrollRequest struct {
+	StudentName string `json:"student_name" binding:"required"`
+	Contact     string `json:"contact"`
+}
+
+func enrollmentResponse(e models.GroupEnrollment) gin.H {
+	return gin.H{
+		"id":               e.ID,
+		"group_session_id": e.GroupSessionID,
+		"student_name":     e.StudentName,
+		"contact":          e.Contact,
+		"status":           e.Status,
+		"price":            e.Price,
+		"created_at":       e.CreatedAt,
+	}
+}
+
+// groupSessionResponse 返回团体课信息，Enrollments 已加载时一并返回报名列表
+func groupSessionResponse(s models.GroupSession) gin.H {
+	enrolled := 0
+	enrollments := make([]gin.H, 0, len(s.Enrollments))
+	for _, e := range s.Enrollments {
+		if e.Status == models.EnrollmentStatusEnrolled {
+			enrolled++
+		}
+		enrollments = append(enrollments, enrollmentResponse(e))
+	}
+	return gin.H{
+		"id":          s.ID,
+		"title":       s.Title,
+		"capacity":    s.Capacity,
+		"enrolled":    enrolled,
+		"remaining":   s.Capacity - enrolled,
+		"booking":     bookingResponse(s.Booking),
+		"enrollments": enrollments,
+	}
+}
+
+// countEnrolled 统计团体课当前有效的报名人数
+func countEnrolled(tx *gorm.DB, sessionID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.GroupEnrollment{}).
+		Where("group_session_id = ? AND status = ?", sessionID, models.EnrollmentStatusEnrolled).
+		Count(&count).Error
+	return count, err
+}
+
+// lockGroupSession 锁定团体课所在行，保证同一时间只有一个事务在计算名额
+func lockGroupSession(tx *gorm.DB, id string) (models.GroupSession, error) {
+	var session models.GroupSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Booking").First(&session, id).Error
+	return session, err
+}
+
+// requireOwnGroupSession 校验路径参数 id 指定的团体课属于当前教练，团体课通过其预约的 coach_id 归属教练；
+// 不受教练范围限制的用户直接放行，失败时写入错误响应
+func requireOwnGroupSession(c *gin.Context) bool {
+	scoped, ok := scopedCoachID(c)
+	if !ok {
+		return true
+	}
+	var owner struct{ CoachID uint }
+	result := database.DB.Model(&models.GroupSession{}).Select("bookings.coach_id").
+		Joins("JOIN bookings ON bookings.id = group_sessions.booking_id").
+		Where("group_sessions.id = ?", c.Param("id")).Limit(1).Scan(&owner)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check ownership"})
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group session not found"})
+		return false
+	}
+	if owner.CoachID != scoped {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. This record belongs to another coach."})
+		return false
+	}
+	return true
+}
+
+func loadGroupSession(id string) (models.GroupSession, error) {
+	var session models.GroupSession
+	err := database.DB.
+		Preload("Booking.Slots").Preload("Booking.Course").
+		Preload("Enrollments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&session, id).Error
+	return session, err
+}
+
+// ListGroupSessionsHandler 查询团体课，可按 coach_id 和 from/to 日期过滤，教练只能看到自己的团体课
+func ListGroupSessionsHandler(c *gin.Context) {
+	db := database.DB.Joins("Booking").
+		Preload("Booking.Slots").Preload("Booking.Course").
+		Preload("Enrollments", "status = ?", models.EnrollmentStatusEnrolled)
+	if scoped, ok := scopedCoachID(c); ok {
+		db = db.Where("Booking.coach_id = ?", scoped)
+	} else if coachID := c.Query("coach_id"); coachID != "" {
+		db = db.Where("Booking.coach_id = ?", coachID)
+	}
+	if from := c.Query("from"); from != "" {
+		db = db.Where("Booking.booking_date >= ?", from)
+	}
+	if to := c.Query("to"); to != "" {
+		db = db.Where("Booking.booking_date <= ?", to)
+	}
+	if c.Query("status") != "all" {
+		db = db.Where("Booking.status <> ?", models.BookingStatusCancelled)
+	}
+	var sessions []models.GroupSession
+	if err := db.Order("Booking.booking_date").Find(&sessions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve group sessions"})
+		return
+	}
+	resp := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		item := groupSessionResponse(s)
+		delete(item, "enrollments")
+		resp = append(resp, item)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGroupSessionHandler 获取团体课详情及报名列表
+func GetGroupSessionHandler(c *gin.Context) {
+	if !requireOwnGroupSession(c) {
+		return
+	}
+	session, err := loadGroupSession(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group session not found"})
+		return
+	}
+	c.JSON(http.StatusOK, groupSessionResponse(session))
+}
+
+// CreateGroupSessionHandler 创建团体课，同时创建一条占用教练时间的预约
+func CreateGroupSessionHandler(c *gin.Context) {
+	var req CreateGroupSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok || !requireOwnCoach(c, req.CoachID) {
+		return
+	}
+	booking, err := newBookingFromRequest(CreateBookingRequest{
+		StudentName: req.Title,
+		CoachID:     req.CoachID,
+		Date:        req.Date,
+		Slots:       req.Slots,
+		TimeSlots:   req.TimeSlots,
+		CourseID:    req.CourseID,
+		StartTime:   req.StartTime,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := models.GroupSession{Title: req.Title, Capacity: req.Capacity}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := insertBooking(tx, &booking, userID); err != nil {
+			return err
+		}
+		session.BookingID = booking.ID
+		return tx.Omit("Booking").Create(&session).Error
+	})
+	if err != nil {
+		respondBookingError(c, err, "Failed to create group session")
+		return
+	}
+	session.Booking = booking
+	c.JSON(http.StatusCreated, gin.H{"message": "Group session created successfully", "group_session": groupSessionResponse(session)})
+}
+
+// UpdateGroupSessionHandler 修改团体课标题或名额，名额不能少于已报名人数
+func UpdateGroupSessionHandler(c *gin.Context) {
+	if !requireOwnGroupSession(c) {
+		return
+	}
+	var req UpdateGroupSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		session, err := lockGroupSession(tx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{}
+		if req.Title != "" {
+			updates["title"] = req.Title
+			if err := tx.Model(&session.Booking).Update("client_info", req.Title).Error; err != nil {
+				return err
+			}
+		}
+		if req.Capacity > 0 {
+			enrolled, err := countEnrolled(tx, session.ID)
+			if err != nil {
+				return err
+			}
+			if int64(req.Capacity) < enrolled {
+				return errCapacityTooSmall
+			}
+			updates["capacity"] = req.Capacity
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&session).Updates(updates).Error
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group session not found"})
+		return
+	case errors.Is(err, errCapacityTooSmall):
+		c.JSON(http.StatusConflict, gin.H{"error": "名额不能少于已报名人数"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group session"})
+		return
+	}
+	session, err := loadGroupSession(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve group session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Group session updated successfully", "group_session": groupSessionResponse(session)})
+}
+
+// EnrollGroupSessionHandler 报名团体课，在事务中锁定团体课并统计名额，避免超额报名
+func EnrollGroupSessionHandler(c *gin.Context) {
+	if !requireOwnGroupSession(c) {
+		return
+	}
+	var req EnrollRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var enrollment models.GroupEnrollment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		session, err := lockGroupSession(tx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		if models.IsFinalBookingStatus(session.Booking.Status) {
+			return errSessionClosed
+		}
+		enrolled, err := countEnrolled(tx, session.ID)
+		if err != nil {
+			return err
+		}
+		if enrolled >= int64(session.Capacity) {
+			return errSessionFull
+		}
+		enrollment = models.GroupEnrollment{
+			GroupSessionID: session.ID,
+			StudentName:    req.StudentName,
+			Contact:        req.Contact,
+			Status:         models.EnrollmentStatusEnrolled,
+			Price:          session.Booking.Price,
+			CreatedBy:      userID,
+		}
+		return tx.Create(&enrollment).Error
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, gin.H{"message": "Enrolled successfully", "enrollment": enrollmentResponse(enrollment)})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group session not found"})
+	case errors.Is(err, errSessionFull):
+		c.JSON(http.StatusConflict, gin.H{"error": "团体课名额已满"})
+	case errors.Is(err, errSessionClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "团体课已结束或已取消，无法报名"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll"})
+	}
+}
+
+// CancelEnrollmentHandler 取消团体课报名，释放名额
+func CancelEnrollmentHandler(c *gin.Context) {
+	if !requireOwnGroupSession(c) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		session, err := lockGroupSession(tx, c.Param("id"))
+		if err != nil {
+			return err
+		}
+		var enrollment models.GroupEnrollment
+		if err := tx.Where("id = ? AND group_session_id = ?", c.Param("enrollmentId"), session.ID).First(&enrollment).Error; err != nil {
+			return errEnrollmentMissing
+		}
+		if enrollment.Status == models.EnrollmentStatusCancelled {
+			return errAlreadyCancelled
+		}
+		return tx.Model(&enrollment).Updates(map[string]interface{}{
+			"status":       models.EnrollmentStatusCancelled,
+			"cancelled_at": time.Now(),
+		}).Error
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Enrollment cancelled successfully"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group session not found"})
+	case errors.Is(err, errEnrollmentMissing):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enrollment not found"})
+	case errors.Is(err, errAlreadyCancelled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Enrollment already cancelled"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel enrollment"})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxReportPhotos 每份课程报告最多附带的照片数
+const maxReportPhotos = 9
+
+// SaveLessonReportRequest 定义了填写课程报告的请求结构，再次提交会整体替换原有内容
+type SaveLessonReportRequest struct {
+	Skills []string `json:"skills"` // 练习的技术要点
+	Level  string   `json:"level"`  // 课后评定的技术等级，填写后同步更新学员资料
+	Notes  string   `json:"notes"`
+	Photos []string `json:"photos"` // 通过 /api/upload 上传后得到的 file_url
+}
+
+// normalizeSkills 去掉空白和重复的技术要点，要点本身不能包含逗号
+func normalizeSkills(skills []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, s := range skills {
+		s = strings.TrimSpace(s)
+		if s == "" || seen[s] {
+			continue
+		}
+		if strings.ContainsAny(s, ",，") {
+			return nil, errors.New("skills must not contain commas")
+		}
+		seen[s] = true
+		result = append(result, s)
+	}
+	if len(strings.Join(result, ",")) > 500 {
+		return nil, errors.New("too many skills")
+	}
+	return result, nil
+}
+
+func splitSkills(skills string) []string {
+	if skills == "" {
+		return []string{}
+	}
+	return strings.Split(skills, ",")
+}
+
+func lessonReportResponse(r models.LessonReport) gin.H {
+	photos := make([]string, 0, len(r.Photos))
+	for _, p := range r.Photos {
+		photos = append(photos, p.URL)
+	}
+	resp := gin.H{
+		"id":         r.ID,
+		"booking_id": r.BookingID,
+		"coach_id":   r.CoachID,
+		"student_id": r.StudentID,
+		"skills":     splitSkills(r.Skills),
+		"level":      r.Level,
+		"notes":      r.Notes,
+		"photos":     photos,
+		"created_at": r.CreatedAt,
+		"updated_at": r.UpdatedAt,
+	}
+	if r.Booking != nil {
+		resp["date"] = r.Booking.BookingDate.Format("2006-01-02")
+		resp["time_slots"] = r.Booking.TimeSlot
+		resp["student_name"] = r.Booking.ClientInfo
+		if r.Booking.Course != nil {
+			resp["course_name"] = r.Booking.Course.Name
+		}
+	}
+	return resp
+}
+
+// ownCoachBooking 按路径参数 id 查找当前教练自己的预约，失败时直接写入错误响应
+func ownCoachBooking(c *gin.Context, coach models.Coach) (models.Booking, bool) {
+	var booking models.Booking
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return booking, false
+	}
+	if err := database.DB.Preload("Course").Where("id = ? AND coach_id = ?", id, coach.ID).First(&booking).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return booking, false
+	}
+	return booking, true
+}
+
+// GetOwnLessonReportHandler 教练查看自己某次课程的报告
+func GetOwnLessonReportHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	booking, ok := ownCoachBooking(c, coach)
+	if !ok {
+		return
+	}
+	var report models.LessonReport
+	if err := database.DB.Preload("Photos").Where("booking_id = ?", booking.ID).First(&report).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lesson report not found"})
+		return
+	}
+	report.Booking = &booking
+	c.JSON(http.StatusOK, lessonReportResponse(report))
+}
+
+// SaveOwnLessonReportHandler 教练为自己已完成的课程填写或修改课程报告
+func SaveOwnLessonReportHandler(c *gin.Context) {
+	coach, ok := currentCoach(c)
+	if !ok {
+		return
+	}
+	booking, ok := ownCoachBooking(c, coach)
+	if !ok {
+		return
+	}
+	if booking.Status != models.BookingStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Lesson reports can only be written for completed bookings"})
+		return
+	}
+	var req SaveLessonReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	skills, err := normalizeSkills(req.Skills)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Photos) > maxReportPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many photos"})
+		return
+	}
+	for _, url := range req.Photos {
+		// 只接受本系统上传的图片
+		if !strings.HasPrefix(url, "/uploads/") || strings.Contains(url, "..") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid photo url: " + url})
+			return
+		}
+	}
+	level := strings.TrimSpace(req.Level)
+
+	var report models.LessonReport
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("booking_id = ?", booking.ID).First(&report).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		report.BookingID = booking.ID
+		report.CoachID = coach.ID
+		report.StudentID = booking.StudentID
+		report.Skills = strings.Join(skills, ",")
+		report.Level = level
+		report.Notes = req.Notes
+		if err := tx.Omit("Photos", "Booking").Save(&report).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("report_id = ?", report.ID).Delete(&models.LessonReportPhoto{}).Error; err != nil {
+			return err
+		}
+		report.Photos = nil
+		for _, url := range req.Photos {
+			photo := models.LessonReportPhoto{ReportID: report.ID, URL: url}
+			if err := tx.Create(&photo).Error; err != nil {
+				return err
+			}
+			report.Photos = append(report.Photos, photo)
+		}
+		// 课后评定的等级即学员当前的等级
+		if level != "" && booking.StudentID != nil {
+			return tx.Model(&models.Student{}).Where("id = ?", *booking.StudentID).Update("level", level).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save lesson report"})
+		return
+	}
+	report.Booking = &booking
+	c.JSON(http.StatusOK, lessonReportResponse(report))
+}
+
+// studentProgress 按时间顺序汇总学员的课程报告，coachID 不为空时只包含该教练填写的报告
+func studentProgress(studentID uint, coachID *uint) ([]gin.H, error) {
+	var reports []models.LessonReport
+	db := database.DB.Preload("Photos").Preload("Booking.Course").
+		Joins("JOIN bookings ON bookings.id = lesson_reports.booking_id").
+		Where("lesson_reports.student_id = ?", studentID)
+	if coachID != nil {
+		db = db.Where("lesson_reports.coach_id = ?", *coachID)
+	}
+	if err := db.Order("bookings.booking_date, lesson_reports.id").Find(&reports).Error; err != nil {
+		return nil, err
+	}
+	coachIDs := make([]uint, 0, len(reports))
+	for _, r := range reports {
+		coachIDs = append(coachIDs, r.CoachID)
+	}
+	var coaches []models.Coach
+	if len(coachIDs) > 0 {
+		if err := database.DB.Select("id", "name").Where("id IN ?", coachIDs).Find(&coaches).Error; err != nil {
+			return nil, err
+		}
+	}
+	coachNames := make(map[uint]string, len(coaches))
+	for _, co := range coaches {
+		coachNames[co.ID] = co.Name
+	}
+	timeline := make([]gin.H, 0, len(reports))
+	for _, r := range reports {
+		item := lessonReportResponse(r)
+		item["coach_name"] = coachNames[r.CoachID]
+		timeline = append(timeline, item)
+	}
+	return timeline, nil
+}
+
+// GetStudentProgressHandler 工作人员查看学员的进度时间线，教练只能看到自己填写的课程报告
+func GetStudentProgressHandler(c *gin.Context) {
+	student, ok := findScopedStudent(c)
+	if !ok {
+		return
+	}
+	var coachID *uint
+	if scoped, ok := scopedCoachID(c); ok {
+		coachID = &scoped
+	}
+	timeline, err := studentProgress(student.ID, coachID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve student progress"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"student": studentResponse(student), "timeline": timeline})
+}
+
+// GetOwnProgressHandler 学员查看自己的进度时间线
+func GetOwnProgressHandler(c *gin.Context) {
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	timeline, err := studentProgress(student.ID, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve progress"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"student": studentResponse(student), "timeline": timeline})
+}
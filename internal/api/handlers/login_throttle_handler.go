@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UnlockLoginRequest 定义了解除登录锁定的请求结构，username 和 ip 至少提供一个
+type UnlockLoginRequest struct {
+	Username string `json:"username"`
+	IP       string `json:"ip"`
+}
+
+func usernameSubject(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// loginConfig 返回登录防暴力破解配置，未配置的项使用默认值
+func loginConfig() config.LoginConfig {
+	cfg := config.Cfg.Login
+	if cfg.FreeAttempts <= 0 {
+		cfg.FreeAttempts = 3
+	}
+	if cfg.MaxBackoffSeconds <= 0 {
+		cfg.MaxBackoffSeconds = 300
+	}
+	if cfg.LockoutThreshold <= 0 {
+		cfg.LockoutThreshold = 10
+	}
+	if cfg.IPLockoutThreshold <= 0 {
+		cfg.IPLockoutThreshold = 50
+	}
+	if cfg.LockoutMinutes <= 0 {
+		cfg.LockoutMinutes = 30
+	}
+	return cfg
+}
+
+// loginRetryAfter 返回这些主体中最晚解除限制的剩余时间，以及是否处于锁定状态
+func loginRetryAfter(subjects ...string) (time.Duration, bool, error) {
+	var throttles []models.LoginThrottle
+	if err := database.DB.Where("subject IN ? AND blocked_until > ?", subjects, time.Now()).Find(&throttles).Error; err != nil {
+		return 0, false, err
+	}
+	var wait time.Duration
+	locked := false
+	for _, t := range throttles {
+		if d := time.Until(*t.BlockedUntil); d > wait {
+			wait = d
+		}
+		locked = locked || t.Locked
+	}
+	return wait, locked, nil
+}
+
+// recordLoginFailure 记录一次登录失败：超过免退避次数后等待时间每次翻倍，达到 threshold 次后锁定
+func recordLoginFailure(subject string, threshold int) error {
+	cfg := loginConfig()
+	lockout := time.Duration(cfg.LockoutMinutes) * time.Minute
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		// 新记录写入当前时间，避免 MySQL 严格模式拒绝零值 DATETIME
+		throttle := models.LoginThrottle{Subject: subject, Failures: 0, LastFailedAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&throttle).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&throttle, "subject = ?", subject).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		// 超过锁定时长没有再失败，重新开始计数
+		if now.Sub(throttle.LastFailedAt) > lockout {
+			throttle.Failures = 0
+			throttle.Locked = false
+		}
+		throttle.Failures++
+		throttle.LastFailedAt = now
+		switch {
+		case throttle.Failures >= threshold:
+			until := now.Add(lockout)
+			throttle.BlockedUntil = &until
+			throttle.Locked = true
+			log.Printf("[LoginThrottle] %s locked until %s after %d failures", subject, until.Format(time.RFC3339), throttle.Failures)
+		case throttle.Failures > cfg.FreeAttempts:
+			backoff := time.Duration(cfg.MaxBackoffSeconds) * time.Second
+			if shift := throttle.Failures - cfg.FreeAttempts - 1; shift < 16 && time.Second<<shift < backoff {
+				backoff = time.Second << shift
+			}
+			until := now.Add(backoff)
+			throttle.BlockedUntil = &until
+		}
+		return tx.Save(&throttle).Error
+	})
+}
+
+// recordLoginFailures 同时记录用户名和IP的失败次数
+func recordLoginFailures(username, ip string) {
+	cfg := loginConfig()
+	if err := recordLoginFailure(usernameSubject(username), cfg.LockoutThreshold); err != nil {
+		log.Printf("[LoginThrottle] failed to record failure: %v", err)
+	}
+	if err := recordLoginFailure(ipSubject(ip), cfg.IPLockoutThreshold); err != nil {
+		log.Printf("[LoginThrottle] failed to record failure: %v", err)
+	}
+}
+
+// clearLoginFailures 登录成功后清除用户名的失败记录；IP 的记录保留，避免攻击者用自己的账号重置计数
+func clearLoginFailures(username string) {
+	if err := database.DB.Delete(&models.LoginThrottle{}, "subject = ?", usernameSubject(username)).Error; err != nil {
+		log.Printf("[LoginThrottle] failed to clear failures: %v", err)
+	}
+}
+
+// respondLoginThrottled 返回 429 和 Retry-After，登录页据此提示剩余等待时间
+func respondLoginThrottled(c *gin.Context, wait time.Duration, locked bool) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	msg := "登录失败次数过多，请稍后再试"
+	if locked {
+		msg = "账号因多次登录失败已被临时锁定，请稍后再试或联系管理员解锁"
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": msg, "retry_after": seconds, "locked": locked})
+}
+
+// ListLoginThrottlesHandler 管理员查看当前被限制登录的用户名和IP
+func ListLoginThrottlesHandler(c *gin.Context) {
+	var throttles []models.LoginThrottle
+	if err := database.DB.Where("blocked_until > ?", time.Now()).Order("blocked_until DESC").Find(&throttles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve login lockouts"})
+		return
+	}
+	resp := make([]gin.H, 0, len(throttles))
+	for _, t := range throttles {
+		resp = append(resp, gin.H{
+			"subject":        t.Subject,
+			"failures":       t.Failures,
+			"locked":         t.Locked,
+			"blocked_until":  t.BlockedUntil,
+			"last_failed_at": t.LastFailedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UnlockLoginHandler 管理员解除用户名或IP的登录限制
+func UnlockLoginHandler(c *gin.Context) {
+	var req UnlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.Username == "" && req.IP == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username or ip is required"})
+		return
+	}
+	var subjects []string
+	if req.Username != "" {
+		subjects = append(subjects, usernameSubject(req.Username))
+	}
+	if req.IP != "" {
+		subjects = append(subjects, ipSubject(req.IP))
+	}
+	result := database.DB.Delete(&models.LoginThrottle{}, "subject IN ?", subjects)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Unlocked successfully", "cleared": result.RowsAffected})
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxPageSize 列表接口单页最多返回的条数
+const maxPageSize = 100
+
+// pagination 是列表接口的分页参数，Size 为 0 表示不分页
+type pagination struct {
+	Page int
+	Size int
+}
+
+// parseIDParam 解析路径中的数字ID参数，失败时直接写入400响应
+func parseIDParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseIDQuery 解析可选的数字ID查询参数，未提供时返回 nil，格式错误时直接写入400响应
+func parseIDQuery(c *gin.Context, name string) (*uint, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return nil, false
+	}
+	v := uint(id)
+	return &v, true
+}
+
+// parseDateRange 解析 from/to 查询参数（YYYY-MM-DD），未提供 to 时与 from 相同，
+// 未提供 from 时默认为今天；maxDays 大于0时限制范围长度
+func parseDateRange(c *gin.Context, maxDays int) (time.Time, time.Time, bool) {
+	from := time.Now()
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	if s := c.Query("from"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format"})
+			return time.Time{}, time.Time{}, false
+		}
+		from = d
+	}
+	to := from
+	if s := c.Query("to"); s != "" {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format"})
+			return time.Time{}, time.Time{}, false
+		}
+		to = d
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be earlier than from"})
+		return time.Time{}, time.Time{}, false
+	}
+	if maxDays > 0 && int(to.Sub(from).Hours()/24)+1 > maxDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Date range must not exceed " + strconv.Itoa(maxDays) + " days"})
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// parsePagination 解析 page（从1开始）和 page_size 查询参数；两者都未提供时不分页，
+// 保持旧接口返回全部结果的行为
+func parsePagination(c *gin.Context) (pagination, bool) {
+	p := pagination{}
+	pageStr, sizeStr := c.Query("page"), c.Query("page_size")
+	if pageStr == "" && sizeStr == "" {
+		return p, true
+	}
+	p.Page, p.Size = 1, 20
+	if pageStr != "" {
+		n, err := strconv.Atoi(pageStr)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return p, false
+		}
+		p.Page = n
+	}
+	if sizeStr != "" {
+		n, err := strconv.Atoi(sizeStr)
+		if err != nil || n <= 0 || n > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page_size must be between 1 and " + strconv.Itoa(maxPageSize)})
+			return p, false
+		}
+		p.Size = n
+	}
+	return p, true
+}
+
+// apply 为查询加上 offset/limit
+func (p pagination) apply(db *gorm.DB) *gorm.DB {
+	if p.Size == 0 {
+		return db
+	}
+	return db.Offset((p.Page - 1) * p.Size).Limit(p.Size)
+}
+
+// setPageHeaders 通过响应头返回符合条件的总数和分页参数，响应体保持为数组
+func setPageHeaders(c *gin.Context, p pagination, total int64) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if p.Size > 0 {
+		c.Header("X-Page", strconv.Itoa(p.Page))
+		c.Header("X-Page-Size", strconv.Itoa(p.Size))
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/notify"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// resetRequestInterval 同一用户两次申请重置之间的最短间隔，避免被用来刷通知
+const resetRequestInterval = time.Minute
+
+var errResetTokenInvalid = errors.New("password reset token is invalid")
+
+// PasswordResetRequest 定义了申请重置密码的请求结构
+type PasswordResetRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// PasswordResetConfirmRequest 定义了使用令牌设置新密码的请求结构
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+func resetTokenTTL() time.Duration {
+	if m := config.Cfg.Password.ResetTokenMinutes; m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return 30 * time.Minute
+}
+
+// resetMessage 生成发送给用户的重置通知，配置了重置页面时附带链接
+func resetMessage(user models.User, token string) notify.Message {
+	body := fmt.Sprintf("您的密码重置令牌为：%s\n有效期 %d 分钟，只能使用一次。如非本人操作请忽略。", token, int(resetTokenTTL().Minutes()))
+	if base := config.Cfg.Password.ResetURL; base != "" {
+		body += "\n重置链接：" + base + "?token=" + url.QueryEscape(token)
+	}
+	return notify.Message{UserID: user.ID, Username: user.Username, Subject: "密码重置", Body: body}
+}
+
+// RequestPasswordResetHandler 为用户生成一次性重置令牌并通过通知渠道发送；
+// 无论用户名是否存在都返回相同的响应，避免泄露账号信息
+func RequestPasswordResetHandler(c *gin.Context) {
+	var req PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	accepted := gin.H{"message": "如果该账号存在，重置令牌已发送"}
+
+	var user models.User
+	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, accepted)
+		return
+	}
+
+	var token string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var recent int64
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-resetRequestInterval)).
+			Count(&recent).Error; err != nil {
+			return err
+		}
+		if recent > 0 {
+			return nil
+		}
+		// 新令牌生成后，之前未使用的令牌全部作废
+		if err := tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.ID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		if token, err = randomToken(32); err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(resetTokenTTL()),
+		}).Error
+	})
+	// 失败只记录日志，响应与账号不存在时一致，避免泄露账号是否存在
+	if err != nil {
+		log.Printf("[PasswordReset] failed to create token for user %d: %v", user.ID, err)
+	} else if token != "" {
+		if err := notify.Send(resetMessage(user, token)); err != nil {
+			log.Printf("[PasswordReset] failed to notify user %d: %v", user.ID, err)
+		}
+	}
+	c.JSON(http.StatusOK, accepted)
+}
+
+// ConfirmPasswordResetHandler 校验重置令牌并设置新密码，同时撤销该用户的所有会话并解除登录锁定
+func ConfirmPasswordResetHandler(c *gin.Context) {
+	var req PasswordResetConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var user models.User
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.PasswordResetToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(req.Token)).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errResetTokenInvalid
+			}
+			return err
+		}
+		if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+			return errResetTokenInvalid
+		}
+		if err := tx.First(&user, record.UserID).Error; err != nil {
+			return errResetTokenInvalid
+		}
+		if err := tx.Model(&record).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Updates(map[string]interface{}{"password_hash": string(hashed), "must_change_password": false}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	switch {
+	case err == nil:
+		clearLoginFailures(user.Username)
+		c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please log in again"})
+	case errors.Is(err, errResetTokenInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+	}
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxReviewCommentLength 评价内容的最大字数
+const maxReviewCommentLength = 1000
+
+// CreateReviewRequest 定义了学员评价课程的请求结构
+type CreateReviewRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment"`
+}
+
+// coachRating 是教练的评分汇总，只统计审核通过的评价
+type coachRating struct {
+	CoachID     uint
+	Average     float64
+	ReviewCount int64
+}
+
+// coachRatings 批量查询教练的平均分和评价数
+func coachRatings(coachIDs []uint) (map[uint]coachRating, error) {
+	result := make(map[uint]coachRating, len(coachIDs))
+	if len(coachIDs) == 0 {
+		return result, nil
+	}
+	var rows []coachRating
+	if err := database.DB.Model(&models.CoachReview{}).
+		Select("coach_id, AVG(rating) AS average, COUNT(*) AS review_count").
+		Where("coach_id IN ? AND status = ?", coachIDs, models.ReviewStatusApproved).
+		Group("coach_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		result[r.CoachID] = r
+	}
+	return result, nil
+}
+
+// withRating 把评分汇总写入教练响应，没有评价时平均分为 null
+func withRating(resp gin.H, r coachRating) gin.H {
+	if r.ReviewCount > 0 {
+		// 保留一位小数
+		resp["rating_average"] = float64(int(r.Average*10+0.5)) / 10
+	} else {
+		resp["rating_average"] = nil
+	}
+	resp["review_count"] = r.ReviewCount
+	return resp
+}
+
+// ratingOrderJoin 用于按评分排序教练列表，没有评价的教练排在最后
+const ratingOrderJoin = "LEFT JOIN (SELECT coach_id, AVG(rating) AS avg_rating, COUNT(*) AS review_count FROM coach_reviews WHERE status = '" +
+	models.ReviewStatusApproved + "' GROUP BY coach_id) ratings ON ratings.coach_id = coaches.id"
+
+// reviewResponse 是评价的公开信息，不包含学员身份
+func reviewResponse(r models.CoachReview) gin.H {
+	return gin.H{
+		"id":         r.ID,
+		"coach_id":   r.CoachID,
+		"rating":     r.Rating,
+		"comment":    r.Comment,
+		"created_at": r.CreatedAt,
+	}
+}
+
+// reviewDetailResponse 是评价的完整信息，供学员本人和管理员查看
+func reviewDetailResponse(r models.CoachReview) gin.H {
+	resp := reviewResponse(r)
+	resp["booking_id"] = r.BookingID
+	resp["student_id"] = r.StudentID
+	resp["status"] = r.Status
+	resp["moderated_at"] = r.ModeratedAt
+	return resp
+}
+
+// isDuplicateKey 判断数据库错误是否为唯一索引冲突
+func isDuplicateKey(err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if t, ok := database.DB.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(t.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// CreateOwnReviewHandler 学员评价自己已完成的课程，每个预约只能评价一次
+func CreateOwnReviewHandler(c *gin.Context) {
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	bookingID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	comment := strings.TrimSpace(req.Comment)
+	if len([]rune(comment)) > maxReviewCommentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Comment is too long"})
+		return
+	}
+
+	var booking models.Booking
+	if err := database.DB.Where("id = ? AND student_id = ?", bookingID, student.ID).First(&booking).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if booking.Status != models.BookingStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only completed lessons can be reviewed"})
+		return
+	}
+	var existing int64
+	if err := database.DB.Model(&models.CoachReview{}).Where("booking_id = ?", booking.ID).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This lesson has already been reviewed"})
+		return
+	}
+
+	review := models.CoachReview{
+		BookingID: booking.ID,
+		CoachID:   booking.CoachID,
+		StudentID: student.ID,
+		Rating:    req.Rating,
+		Comment:   comment,
+		Status:    models.ReviewStatusPending,
+	}
+	if err := database.DB.Create(&review).Error; err != nil {
+		// 并发提交时由唯一索引兜底
+		if isDuplicateKey(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This lesson has already been reviewed"})
+			return
+		}
+		log.Printf("[Review] failed to create review for booking %d: %v", booking.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	c.JSON(http.StatusCreated, reviewDetailResponse(review))
+}
+
+// ListOwnReviewsHandler 学员查看自己提交的评价及审核状态
+func ListOwnReviewsHandler(c *gin.Context) {
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	var reviews []models.CoachReview
+	if err := database.DB.Where("student_id = ?", student.ID).Order("id DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+	resp := make([]gin.H, 0, len(reviews))
+	for _, r := range reviews {
+		resp = append(resp, reviewDetailResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListCoachReviewsHandler 公开查看教练审核通过的评价，按时间倒序
+func ListCoachReviewsHandler(c *gin.Context) {
+	coachID, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := database.DB.First(&models.Coach{}, coachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+	var reviews []models.CoachReview
+	if err := database.DB.Where("coach_id = ? AND status = ?", coachID, models.ReviewStatusApproved).
+		Order("created_at DESC, id DESC").Limit(limit).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+	ratings, err := coachRatings([]uint{coachID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+	items := make([]gin.H, 0, len(reviews))
+	for _, r := range reviews {
+		items = append(items, reviewResponse(r))
+	}
+	c.JSON(http.StatusOK, withRating(gin.H{"coach_id": coachID, "reviews": items}, ratings[coachID]))
+}
+
+// ListReviewsHandler 管理员按状态和教练查看评价，默认列出待审核的评价
+func ListReviewsHandler(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+	db := database.DB.Model(&models.CoachReview{})
+	switch status {
+	case models.ReviewStatusPending, models.ReviewStatusApproved, models.ReviewStatusHidden:
+		db = db.Where("status = ?", status)
+	case "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	if s := c.Query("coach_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coach_id"})
+			return
+		}
+		db = db.Where("coach_id = ?", id)
+	}
+	var reviews []models.CoachReview
+	if err := db.Order("id DESC").Limit(200).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+		return
+	}
+	resp := make([]gin.H, 0, len(reviews))
+	for _, r := range reviews {
+		resp = append(resp, reviewDetailResponse(r))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// moderateReview 修改评价的审核状态并记录审核人
+func moderateReview(c *gin.Context, status string) {
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	var review models.CoachReview
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&review, id).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		review.Status = status
+		review.ModeratedBy = &userID
+		review.ModeratedAt = &now
+		return tx.Save(&review).Error
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, reviewDetailResponse(review))
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+	}
+}
+
+// ApproveReviewHandler 审核通过评价，公开展示并计入教练评分
+func ApproveReviewHandler(c *gin.Context) {
+	moderateReview(c, models.ReviewStatusApproved)
+}
+
+// HideReviewHandler 隐藏评价，不再公开展示也不计入评分
+func HideReviewHandler(c *gin.Context) {
+	moderateReview(c, models.ReviewStatusHidden)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// roleNamePattern 限制角色名为小写字母、数字和下划线
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+var errRoleInUse = errors.New("role is assigned to users")
+
+// SaveRoleRequest 定义了创建或修改角色的请求结构，permissions 会整体替换角色原有的权限
+type SaveRoleRequest struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+func roleResponse(r models.Role) gin.H {
+	perms := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		perms = append(perms, p.Permission)
+	}
+	return gin.H{
+		"name":        r.Name,
+		"description": r.Description,
+		"built_in":    r.BuiltIn,
+		"permissions": perms,
+	}
+}
+
+// rolePermissions 查询角色拥有的权限
+func rolePermissions(tx *gorm.DB, roleName string) (map[string]bool, error) {
+	var perms []string
+	if err := tx.Model(&models.RolePermission{}).Where("role_name = ?", roleName).Pluck("permission", &perms).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	return set, nil
+}
+
+// coversPermissions 判断 held 是否包含 perms 中的全部权限；拥有 "*" 视为包含一切，"*" 本身只能由 "*" 覆盖
+func coversPermissions(held map[string]bool, perms []string) bool {
+	if held[models.PermissionAll] {
+		return true
+	}
+	for _, p := range perms {
+		if !held[p] {
+			return false
+		}
+	}
+	return true
+}
+
+// callerPermissions 查询当前登录用户角色的权限
+func callerPermissions(c *gin.Context) (map[string]bool, error) {
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+	return rolePermissions(database.DB, roleName)
+}
+
+// requireGrantable 只允许授予当前用户自己拥有的权限，避免通过角色管理或账号管理提升权限；
+// 失败时直接写入错误响应
+func requireGrantable(c *gin.Context, perms []string) bool {
+	held, err := callerPermissions(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return false
+	}
+	if !coversPermissions(held, perms) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot grant permissions you do not have"})
+		return false
+	}
+	return true
+}
+
+// ListRolesHandler 列出所有角色及其权限，同时返回可分配的权限列表
+func ListRolesHandler(c *gin.Context) {
+	var roles []models.Role
+	if err := database.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve roles"})
+		return
+	}
+	resp := make([]gin.H, 0, len(roles))
+	for _, r := range roles {
+		resp = append(resp, roleResponse(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": resp, "permissions": models.AllPermissions})
+}
+
+// SaveRoleHandler 创建角色或替换已有角色的权限；管理员角色的权限不能修改，避免误操作后无人能管理系统。
+// 只能授予自己拥有的权限，授予 "*" 需要自己拥有 "*"
+func SaveRoleHandler(c *gin.Context) {
+	name := c.Param("name")
+	if !roleNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role name"})
+		return
+	}
+	if name == models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The admin role cannot be modified"})
+		return
+	}
+	var req SaveRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	for _, p := range req.Permissions {
+		if !models.IsValidPermission(p) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + p})
+			return
+		}
+	}
+	if !requireGrantable(c, req.Permissions) {
+		return
+	}
+
+	var role models.Role
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.First(&role, "name = ?", name).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			role = models.Role{Name: name, Description: req.Description}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&role).Update("description", req.Description).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("role_name = ?", name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		for _, p := range req.Permissions {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if err := tx.Create(&models.RolePermission{RoleName: name, Permission: p}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Preload("Permissions").First(&role, "name = ?", name).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save role"})
+		return
+	}
+	c.JSON(http.StatusOK, roleResponse(role))
+}
+
+// DeleteRoleHandler 删除自定义角色，内置角色和仍有用户使用的角色不能删除
+func DeleteRoleHandler(c *gin.Context) {
+	name := c.Param("name")
+	var role models.Role
+	if err := database.DB.First(&role, "name = ?", name).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if role.BuiltIn {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Built-in roles cannot be deleted"})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var users int64
+		if err := tx.Model(&models.User{}).Where("role = ?", name).Count(&users).Error; err != nil {
+			return err
+		}
+		if users > 0 {
+			return errRoleInUse
+		}
+		if err := tx.Where("role_name = ?", name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&role).Error
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Role deleted successfully"})
+	case errors.Is(err, errRoleInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is still assigned to users"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+	}
+}
+
+// GetOwnPermissionsHandler 返回当前用户的角色和权限，前端据此显示或隐藏功能入口
+func GetOwnPermissionsHandler(c *gin.Context) {
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+	var perms []string
+	if err := database.DB.Model(&models.RolePermission{}).Where("role_name = ?", roleName).Order("permission").Pluck("permission", &perms).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve permissions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"role": roleName, "permissions": perms})
+}
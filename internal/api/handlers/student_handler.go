@@ -0,0 +1,630 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	phonePattern = regexp.MustCompile(`^\+?[0-9]{6,20}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+)
+
+// RegisterStudentRequest 定义了学员注册的请求结构，phone 和 email 至少提供一个，用作登录用户名
+type RegisterStudentRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Phone    string `json:"phone"`
+	Email    string `json:"email"`
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// SelfBookingRequest 定义了学员自助预约的请求结构
+type SelfBookingRequest struct {
+	CoachID   uint            `json:"coach_id" binding:"required"`
+	Date      string          `json:"date" binding:"required"` // YYYY-MM-DD
+	Slots     []schedule.Slot `json:"slots"`
+	StartTime string          `json:"start_time"` // 课程有固定时长时，可只提供开始时间
+	CourseID  *uint           `json:"course_id"`
+}
+
+// SelfCancelRequest 定义了学员取消预约的请求结构
+type SelfCancelRequest struct {
+	Reason string `json:"reason"`
+}
+
+func studentConfig() config.StudentConfig {
+	cfg := config.Cfg.Student
+	if cfg.BookingStatus == "" {
+		cfg.BookingStatus = models.BookingStatusPending
+	}
+	if cfg.MaxAdvanceDays <= 0 {
+		cfg.MaxAdvanceDays = 60
+	}
+	if cfg.CancelHours < 0 {
+		cfg.CancelHours = 0
+	}
+	if cfg.MaxOpenBookings <= 0 {
+		cfg.MaxOpenBookings = 5
+	}
+	if cfg.RegisterLimit <= 0 {
+		cfg.RegisterLimit = 10
+	}
+	return cfg
+}
+
+var errTooManyOpenBookings = errors.New("too many open bookings")
+
+// registrationSubject 返回注册频率限制使用的主体，与登录限制共用 login_throttles 表
+func registrationSubject(ip string) string {
+	return "register:" + ip
+}
+
+// checkOpenBookingLimit 锁定学员后统计其未开始的待确认和已确认预约，达到上限时返回 errTooManyOpenBookings
+// 需要在写入预约的事务中调用，避免并发请求同时通过检查
+func checkOpenBookingLimit(tx *gorm.DB, studentID uint, limit int) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Student{}, studentID).Error; err != nil {
+		return err
+	}
+	var open int64
+	if err := tx.Model(&models.Booking{}).
+		Where("student_id = ? AND status IN ? AND booking_date >= ?", studentID,
+			[]string{models.BookingStatusPending, models.BookingStatusConfirmed}, time.Now().Format("2006-01-02")).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open >= int64(limit) {
+		return errTooManyOpenBookings
+	}
+	return nil
+}
+
+// CreateStudentRequest 定义了工作人员录入学员的请求结构
+type CreateStudentRequest struct {
+	Name          string `json:"name" binding:"required"`
+	Phone         string `json:"phone"`
+	Email         string `json:"email"`
+	BirthDate     string `json:"birth_date"` // YYYY-MM-DD
+	Discipline    string `json:"discipline"` // ski、snowboard 或 both
+	Level         string `json:"level"`
+	GuardianName  string `json:"guardian_name"`
+	GuardianPhone string `json:"guardian_phone"`
+	Notes         string `json:"notes"`
+}
+
+// UpdateStudentRequest 定义了修改学员资料的请求结构，未提供的字段保持不变，空字符串表示清空
+type UpdateStudentRequest struct {
+	Name          *string `json:"name"`
+	Phone         *string `json:"phone"`
+	Email         *string `json:"email"`
+	BirthDate     *string `json:"birth_date"`
+	Discipline    *string `json:"discipline"`
+	Level         *string `json:"level"`
+	GuardianName  *string `json:"guardian_name"`
+	GuardianPhone *string `json:"guardian_phone"`
+	Notes         *string `json:"notes"`
+}
+
+// studentAge 按出生日期计算周岁，未填写时返回 nil
+func studentAge(birth *time.Time, now time.Time) *int {
+	if birth == nil {
+		return nil
+	}
+	age := now.Year() - birth.Year()
+	if now.Month() < birth.Month() || (now.Month() == birth.Month() && now.Day() < birth.Day()) {
+		age--
+	}
+	return &age
+}
+
+func studentResponse(s models.Student) gin.H {
+	var birthDate string
+	if s.BirthDate != nil {
+		birthDate = s.BirthDate.Format("2006-01-02")
+	}
+	return gin.H{
+		"id":             s.ID,
+		"user_id":        s.UserID,
+		"name":           s.Name,
+		"phone":          s.Phone,
+		"email":          s.Email,
+		"birth_date":     birthDate,
+		"age":            studentAge(s.BirthDate, time.Now()),
+		"discipline":     s.Discipline,
+		"level":          s.Level,
+		"guardian_name":  s.GuardianName,
+		"guardian_phone": s.GuardianPhone,
+		"notes":          s.Notes,
+		"created_at":     s.CreatedAt,
+	}
+}
+
+// applyStudentUpdate 校验并写入学员资料，返回的错误可直接展示给调用方
+func applyStudentUpdate(s *models.Student, req UpdateStudentRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return errors.New("name must not be empty")
+		}
+		s.Name = name
+	}
+	if req.Phone != nil {
+		phone := normalizePhone(*req.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return errors.New("invalid phone number")
+		}
+		s.Phone = phone
+	}
+	if req.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*req.Email))
+		if email != "" && !emailPattern.MatchString(email) {
+			return errors.New("invalid email")
+		}
+		s.Email = email
+	}
+	if req.BirthDate != nil {
+		s.BirthDate = nil
+		if *req.BirthDate != "" {
+			d, err := time.Parse("2006-01-02", *req.BirthDate)
+			if err != nil || d.After(time.Now()) {
+				return errors.New("invalid birth_date")
+			}
+			s.BirthDate = &d
+		}
+	}
+	if req.Discipline != nil {
+		if *req.Discipline != "" && !models.IsValidDiscipline(*req.Discipline) {
+			return errors.New("invalid discipline")
+		}
+		s.Discipline = *req.Discipline
+	}
+	if req.Level != nil {
+		s.Level = strings.TrimSpace(*req.Level)
+	}
+	if req.GuardianName != nil {
+		s.GuardianName = strings.TrimSpace(*req.GuardianName)
+	}
+	if req.GuardianPhone != nil {
+		phone := normalizePhone(*req.GuardianPhone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return errors.New("invalid guardian_phone")
+		}
+		s.GuardianPhone = phone
+	}
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+	return nil
+}
+
+// completedLessonCounts 统计学员已完成的课程数
+func completedLessonCounts(studentIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64)
+	if len(studentIDs) == 0 {
+		return counts, nil
+	}
+	var rows []struct {
+		StudentID uint
+		Count     int64
+	}
+	if err := database.DB.Model(&models.Booking{}).Select("student_id, COUNT(*) AS count").
+		Where("student_id IN ? AND status = ?", studentIDs, models.BookingStatusCompleted).
+		Group("student_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		counts[r.StudentID] = r.Count
+	}
+	return counts, nil
+}
+
+// normalizePhone 去掉手机号中的空格和连字符
+func normalizePhone(phone string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(phone))
+}
+
+// normalizeContact 校验并规范化手机号和邮箱
+func normalizeContact(phone, email string) (string, string, error) {
+	phone = normalizePhone(phone)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if phone == "" && email == "" {
+		return "", "", errors.New("phone or email is required")
+	}
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return "", "", errors.New("invalid phone number")
+	}
+	if email != "" && !emailPattern.MatchString(email) {
+		return "", "", errors.New("invalid email")
+	}
+	return phone, email, nil
+}
+
+// lessonStart 返回预约中最早时间段的开始时间
+func lessonStart(b models.Booking) time.Time {
+	start := ""
+	for _, s := range b.Slots {
+		if start == "" || s.StartTime < start {
+			start = s.StartTime
+		}
+	}
+	t, err := time.ParseInLocation("15:04", start, time.Local)
+	if err != nil {
+		return time.Date(b.BookingDate.Year(), b.BookingDate.Month(), b.BookingDate.Day(), 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(b.BookingDate.Year(), b.BookingDate.Month(), b.BookingDate.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+}
+
+// currentStudent 查找当前登录用户对应的学员记录，失败时直接写入错误响应
+func currentStudent(c *gin.Context) (models.Student, bool) {
+	var student models.Student
+	userID, ok := currentUserID(c)
+	if !ok {
+		return student, false
+	}
+	if err := database.DB.Where("user_id = ?", userID).First(&student).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Student not found"})
+		return student, false
+	}
+	return student, true
+}
+
+// RegisterStudentHandler 学员自助注册，手机号优先作为登录用户名，注册成功后直接登录
+func RegisterStudentHandler(c *gin.Context) {
+	if !studentConfig().AllowRegistration {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Student registration is closed"})
+		return
+	}
+	var req RegisterStudentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	phone, email, err := normalizeContact(req.Phone, req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 同一IP的注册次数按登录失败的方式退避和锁定，防止批量注册账号
+	subject := registrationSubject(c.ClientIP())
+	wait, _, err := loginRetryAfter(subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+	if wait > 0 {
+		seconds := int((wait + time.Second - 1) / time.Second)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "注册过于频繁，请稍后再试", "retry_after": seconds})
+		return
+	}
+	if err := recordLoginFailure(subject, studentConfig().RegisterLimit); err != nil {
+		log.Printf("[Student] failed to record registration attempt: %v", err)
+	}
+	username := phone
+	if username == "" {
+		username = email
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	user := models.User{Username: username, PasswordHash: string(hashed), Role: models.RoleStudent}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		student := models.Student{UserID: &user.ID, Name: strings.TrimSpace(req.Name), Phone: phone, Email: email}
+		return tx.Create(&student).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This phone or email is already registered"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register"})
+		return
+	}
+	log.Printf("[Student] registered user_id=%d", user.ID)
+
+	if twoFactorRequired(user) {
+		respondSecondFactor(c, user)
+		return
+	}
+	completeLogin(c, user, gin.H{"username": username})
+}
+
+// GetOwnStudentProfileHandler 学员查看自己的资料
+func GetOwnStudentProfileHandler(c *gin.Context) {
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, studentResponse(student))
+}
+
+// ListOwnBookingsHandler 学员查看自己的预约，upcoming=true 时只返回今天及以后未取消的预约
+func ListOwnBookingsHandler(c *gin.Context) {
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	db := database.DB.Preload("Slots").Preload("Course").Where("student_id = ?", student.ID)
+	if upcoming, _ := strconv.ParseBool(c.Query("upcoming")); upcoming {
+		db = db.Where("booking_date >= ? AND status IN ?", time.Now().Format("2006-01-02"),
+			[]string{models.BookingStatusPending, models.BookingStatusConfirmed})
+	}
+	var bookings []models.Booking
+	if err := db.Order("booking_date DESC, id DESC").Find(&bookings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve bookings"})
+		return
+	}
+	c.JSON(http.StatusOK, bookingsResponse(bookings))
+}
+
+// CreateOwnBookingHandler 学员选择教练和空闲时间段自助预约，与后台预约使用相同的工作时间和冲突校验
+func CreateOwnBookingHandler(c *gin.Context) {
+	var req SelfBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.First(&models.Coach{}, req.CoachID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+	cfg := studentConfig()
+	booking, err := newBookingFromRequest(CreateBookingRequest{
+		StudentID: &student.ID,
+		CoachID:   req.CoachID,
+		Date:      req.Date,
+		Slots:     req.Slots,
+		StartTime: req.StartTime,
+		CourseID:  req.CourseID,
+		Status:    cfg.BookingStatus,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if start := lessonStart(booking); !start.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot book a lesson in the past"})
+		return
+	} else if start.After(time.Now().AddDate(0, 0, cfg.MaxAdvanceDays)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lessons can be booked at most " + strconv.Itoa(cfg.MaxAdvanceDays) + " days in advance"})
+		return
+	}
+
+	userID, _ := currentUserID(c)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkOpenBookingLimit(tx, student.ID, cfg.MaxOpenBookings); err != nil {
+			return err
+		}
+		return insertBooking(tx, &booking, userID)
+	})
+	if errors.Is(err, errTooManyOpenBookings) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You can have at most " + strconv.Itoa(cfg.MaxOpenBookings) + " upcoming bookings"})
+		return
+	}
+	if err != nil {
+		respondBookingError(c, err, "Failed to create booking")
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Booking created successfully", "booking": bookingResponse(booking)})
+}
+
+// CancelOwnBookingHandler 学员取消自己的预约，课程开始前 cancel_hours 小时内需要联系前台取消
+func CancelOwnBookingHandler(c *gin.Context) {
+	var req SelfCancelRequest
+	// 请求体可以为空
+	_ = c.ShouldBindJSON(&req)
+	student, ok := currentStudent(c)
+	if !ok {
+		return
+	}
+	var booking models.Booking
+	if err := database.DB.Preload("Slots").Where("id = ? AND student_id = ?", c.Param("id"), student.ID).First(&booking).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	cancelHours := studentConfig().CancelHours
+	if time.Until(lessonStart(booking)) < time.Duration(cancelHours)*time.Hour {
+		c.JSON(http.StatusConflict, gin.H{"error": "课程开始前 " + strconv.Itoa(cancelHours) + " 小时内不能自行取消，请联系前台"})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		reason = "学员自行取消"
+	}
+	userID, _ := currentUserID(c)
+	// 候补学员的信息不返回给取消的学员
+	booking, _, err := changeBookingStatus(c.Param("id"), models.BookingStatusCancelled, reason, userID)
+	if err != nil {
+		respondBookingStatusError(c, err, booking, models.BookingStatusCancelled)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Booking cancelled successfully", "booking": bookingResponse(booking)})
+}
+
+// coachStudentIDs 返回在该教练名下有过预约的学员ID子查询
+func coachStudentIDs(coachID uint) *gorm.DB {
+	return database.DB.Model(&models.Booking{}).Select("student_id").
+		Where("coach_id = ? AND student_id IS NOT NULL", coachID)
+}
+
+// findScopedStudent 按路径参数 id 查找学员，教练只能查看在自己名下有过预约的学员，失败时直接写入错误响应
+func findScopedStudent(c *gin.Context) (models.Student, bool) {
+	var student models.Student
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return student, false
+	}
+	db := database.DB
+	if scoped, ok := scopedCoachID(c); ok {
+		db = db.Where("id IN (?)", coachStudentIDs(scoped))
+	}
+	if err := db.First(&student, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Student not found"})
+		return student, false
+	}
+	return student, true
+}
+
+// SearchStudentsHandler 按姓名、手机号、邮箱或监护人电话搜索学员，供预约时选择已有学员；
+// 教练只能搜索到在自己名下有过预约的学员
+func SearchStudentsHandler(c *gin.Context) {
+	db := database.DB.Model(&models.Student{})
+	if scoped, ok := scopedCoachID(c); ok {
+		db = db.Where("id IN (?)", coachStudentIDs(scoped))
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + q + "%"
+		prefix := q + "%"
+		db = db.Where("name LIKE ? OR phone LIKE ? OR email LIKE ? OR guardian_phone LIKE ?", like, prefix, prefix, prefix)
+	}
+	if discipline := c.Query("discipline"); discipline != "" {
+		db = db.Where("discipline IN ?", []string{discipline, models.DisciplineBoth})
+	}
+	limit := 20
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+	var students []models.Student
+	if err := db.Order("name, id").Limit(limit).Find(&students).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search students"})
+		return
+	}
+	ids := make([]uint, 0, len(students))
+	for _, s := range students {
+		ids = append(ids, s.ID)
+	}
+	counts, err := completedLessonCounts(ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search students"})
+		return
+	}
+	resp := make([]gin.H, 0, len(students))
+	for _, s := range students {
+		item := studentResponse(s)
+		item["completed_lessons"] = counts[s.ID]
+		resp = append(resp, item)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetStudentHandler 查看学员资料、各状态的课程数和最近的预约，教练只能看到自己名下的预约
+func GetStudentHandler(c *gin.Context) {
+	student, ok := findScopedStudent(c)
+	if !ok {
+		return
+	}
+	bookings := database.DB.Where("student_id = ?", student.ID)
+	if scoped, ok := scopedCoachID(c); ok {
+		bookings = bookings.Where("coach_id = ?", scoped)
+	}
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := bookings.Session(&gorm.Session{}).Model(&models.Booking{}).Select("status, COUNT(*) AS count").
+		Group("status").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve student history"})
+		return
+	}
+	lessonCounts := make(map[string]int64)
+	for _, r := range rows {
+		lessonCounts[r.Status] = r.Count
+	}
+	var recent []models.Booking
+	if err := bookings.Session(&gorm.Session{}).Preload("Slots").Preload("Course").
+		Order("booking_date DESC, id DESC").Limit(20).Find(&recent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve student history"})
+		return
+	}
+	resp := studentResponse(student)
+	resp["lesson_counts"] = lessonCounts
+	resp["completed_lessons"] = lessonCounts[models.BookingStatusCompleted]
+	resp["recent_bookings"] = bookingsResponse(recent)
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateStudentHandler 工作人员录入学员资料
+func CreateStudentHandler(c *gin.Context) {
+	var req CreateStudentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	var student models.Student
+	if err := applyStudentUpdate(&student, UpdateStudentRequest{
+		Name:          &req.Name,
+		Phone:         &req.Phone,
+		Email:         &req.Email,
+		BirthDate:     &req.BirthDate,
+		Discipline:    &req.Discipline,
+		Level:         &req.Level,
+		GuardianName:  &req.GuardianName,
+		GuardianPhone: &req.GuardianPhone,
+		Notes:         &req.Notes,
+	}); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&student).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create student"})
+		return
+	}
+	c.JSON(http.StatusCreated, studentResponse(student))
+}
+
+// UpdateStudentHandler 修改学员资料，例如更新技术等级；教练只能修改在自己名下有过预约的学员
+func UpdateStudentHandler(c *gin.Context) {
+	student, ok := findScopedStudent(c)
+	if !ok {
+		return
+	}
+	var req UpdateStudentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if err := applyStudentUpdate(&student, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(&student).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update student"})
+		return
+	}
+	c.JSON(http.StatusOK, studentResponse(student))
+}
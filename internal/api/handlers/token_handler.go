@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errRefreshTokenInvalid = errors.New("refresh token is invalid")
+
+// RefreshRequest 定义了刷新令牌的请求结构
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest 定义了退出登录的请求结构，all 为 true 时退出该用户的所有会话
+type LogoutRequest struct {
+	All bool `json:"all"`
+}
+
+// accessTokenTTL 返回访问令牌有效期，未配置分钟数时兼容旧的 expiration（小时）
+func accessTokenTTL() time.Duration {
+	cfg := config.Cfg.JWT
+	if cfg.AccessTokenMinutes > 0 {
+		return time.Duration(cfg.AccessTokenMinutes) * time.Minute
+	}
+	if cfg.Expiration > 0 {
+		return time.Duration(cfg.Expiration) * time.Hour
+	}
+	return 15 * time.Minute
+}
+
+func refreshTokenTTL() time.Duration {
+	if days := config.Cfg.JWT.RefreshTokenDays; days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+// randomToken 生成 n 字节的随机数并以十六进制返回
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken 为会话生成新的刷新令牌，数据库只保存摘要
+func issueRefreshToken(tx *gorm.DB, sessionID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	record := models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTTL()),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// startSession 为登录成功的用户创建会话，并签发访问令牌和刷新令牌
+func startSession(c *gin.Context, user models.User) (LoginResponse, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	var refreshToken string
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		session := models.AuthSession{ID: sessionID, UserID: user.ID, UserAgent: truncate(c.Request.UserAgent(), 255), IP: c.ClientIP()}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		refreshToken, err = issueRefreshToken(tx, sessionID)
+		return err
+	})
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	token, err := generateJWT(user, sessionID)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	return LoginResponse{
+		Token:              token,
+		RefreshToken:       refreshToken,
+		ExpiresIn:          int(accessTokenTTL().Seconds()),
+		Role:               user.Role,
+		MustChangePassword: user.MustChangePassword,
+	}, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// revokeSession 撤销单个会话
+func revokeSession(tx *gorm.DB, sessionID string) error {
+	return tx.Model(&models.AuthSession{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// revokeUserSessions 撤销用户的所有会话，例如删除教练或怀疑账号泄露时
+func revokeUserSessions(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.AuthSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RefreshTokenHandler 使用刷新令牌换取新的访问令牌，同时换发新的刷新令牌；
+// 已使用过的刷新令牌再次出现时，视为令牌泄露并撤销整个会话
+func RefreshTokenHandler(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	var user models.User
+	var sessionID, refreshToken string
+	reused := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(req.RefreshToken)).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errRefreshTokenInvalid
+			}
+			return err
+		}
+		sessionID = record.SessionID
+		var session models.AuthSession
+		if err := tx.First(&session, "id = ?", record.SessionID).Error; err != nil || session.RevokedAt != nil {
+			return errRefreshTokenInvalid
+		}
+		if record.UsedAt != nil {
+			// 撤销会话需要提交，因此这里不返回错误
+			log.Printf("[Auth] refresh token reused, revoking session %s", session.ID)
+			reused = true
+			return revokeSession(tx, session.ID)
+		}
+		if time.Now().After(record.ExpiresAt) {
+			return errRefreshTokenInvalid
+		}
+		if err := tx.First(&user, session.UserID).Error; err != nil || user.Disabled {
+			return errRefreshTokenInvalid
+		}
+		if err := tx.Model(&record).Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		var err error
+		refreshToken, err = issueRefreshToken(tx, session.ID)
+		return err
+	})
+	switch {
+	case err == nil && !reused:
+	case err == nil, errors.Is(err, errRefreshTokenInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	token, err := generateJWT(user, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{
+		Token:              token,
+		RefreshToken:       refreshToken,
+		ExpiresIn:          int(accessTokenTTL().Seconds()),
+		Role:               user.Role,
+		MustChangePassword: user.MustChangePassword,
+	})
+}
+
+// LogoutHandler 撤销当前会话，all 为 true 时撤销当前用户的所有会话
+func LogoutHandler(c *gin.Context) {
+	var req LogoutRequest
+	// 请求体可以为空
+	_ = c.ShouldBindJSON(&req)
+
+	var err error
+	if req.All {
+		userID, ok := currentUserID(c)
+		if !ok {
+			return
+		}
+		err = revokeUserSessions(database.DB, userID)
+	} else {
+		err = revokeSession(database.DB, c.GetString("session_id"))
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// RevokeCoachSessionsHandler 管理员撤销教练的所有会话，例如教练手机丢失时
+func RevokeCoachSessionsHandler(c *gin.Context) {
+	var coach models.Coach
+	if err := database.DB.First(&coach, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	}
+	if err := revokeUserSessions(database.DB, coach.UserID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions of the coach have been revoked"})
+}
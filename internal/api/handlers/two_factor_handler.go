@@ -0,0 +1,474 @@
+package handlers
+
+import (
+	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/totp"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mfaTokenTTL 是密码验证通过后、完成第二步验证前临时令牌的有效期
+const mfaTokenTTL = 5 * time.Minute
+
+const recoveryCodeCount = 10
+
+var (
+	errMFATokenInvalid     = errors.New("mfa token is invalid")
+	errSecondFactorInvalid = errors.New("second factor is invalid")
+	errTOTPAlreadyEnabled  = errors.New("two-factor authentication already enabled")
+	errTOTPNotSetup        = errors.New("two-factor authentication has not been set up")
+	errTOTPRequired        = errors.New("two-factor authentication is required for this role")
+)
+
+// MFALoginRequest 定义了登录第二步的请求结构，code 可以是验证码或恢复码
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFASetupRequest 定义了登录过程中设置两步验证的请求结构
+type MFASetupRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+// TOTPCodeRequest 定义了已登录用户提交验证码的请求结构
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTOTPRequest 定义了关闭两步验证的请求结构，需要同时提供密码和验证码
+type DisableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// twoFactorRequired 判断用户所属角色是否必须启用两步验证
+func twoFactorRequired(user models.User) bool {
+	for _, role := range config.Cfg.TwoFactor.RequiredRoles {
+		if role == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+func totpIssuer() string {
+	if issuer := config.Cfg.TwoFactor.Issuer; issuer != "" {
+		return issuer
+	}
+	return "ClassOrder"
+}
+
+// generateMFAToken 签发只能用于完成第二步验证的临时令牌；
+// 该令牌没有 sid，JWTAuthMiddleware 不会接受它访问其他接口
+func generateMFAToken(user models.User) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID,
+		"typ":     "mfa",
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
+		"iat":     time.Now().Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.Cfg.JWT.Secret))
+}
+
+// userFromMFAToken 校验临时令牌并返回对应的用户
+func userFromMFAToken(tokenString string) (models.User, error) {
+	var user models.User
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errMFATokenInvalid
+		}
+		return []byte(config.Cfg.JWT.Secret), nil
+	})
+	if err != nil || !token.Valid {
+		return user, errMFATokenInvalid
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "mfa" {
+		return user, errMFATokenInvalid
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return user, errMFATokenInvalid
+	}
+	// 输入密码后账号被停用的，同样拒绝
+	if err := database.DB.First(&user, uint(userID)).Error; err != nil || user.Disabled {
+		return user, errMFATokenInvalid
+	}
+	return user, nil
+}
+
+// respondSecondFactor 密码验证通过但还需要第二步验证时，返回临时令牌而不是正式令牌
+func respondSecondFactor(c *gin.Context, user models.User) {
+	mfaToken, err := generateMFAToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":            true,
+		"mfa_enrollment_required": !user.TOTPEnabled,
+		"mfa_token":               mfaToken,
+		"role":                    user.Role,
+	})
+}
+
+// completeLogin 创建会话并返回正式令牌，extra 中的字段会一并返回
+func completeLogin(c *gin.Context, user models.User, extra gin.H) {
+	resp, err := startSession(c, user)
+	if err != nil {
+		log.Printf("生成JWT失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	clearLoginFailures(user.Username)
+	if err := database.DB.Model(&user).Update("last_login_at", time.Now()).Error; err != nil {
+		log.Printf("[Auth] failed to record last login of user %d: %v", user.ID, err)
+	}
+	log.Printf("登录成功: user_id=%d, role=%s", user.ID, user.Role)
+	body := gin.H{
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+		"expires_in":    resp.ExpiresIn,
+		"role":          resp.Role,
+	}
+	if resp.MustChangePassword {
+		body["must_change_password"] = true
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// generateRecoveryCodes 重新生成恢复码，旧的恢复码全部作废；明文只在这里返回一次
+func generateRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := randomToken(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{UserID: userID, CodeHash: hashToken(code)})
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor 在事务中校验验证码或恢复码，成功后记录时间步或标记恢复码已使用
+func verifySecondFactor(tx *gorm.DB, user *models.User, code string) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, user.ID).Error; err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return errTOTPNotSetup
+	}
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep); ok {
+		user.TOTPLastStep = step
+		return tx.Model(user).Update("totp_last_step", step).Error
+	}
+	var recovery models.RecoveryCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(strings.ToLower(code))).
+		First(&recovery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errSecondFactorInvalid
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[TwoFactor] user %d used a recovery code", user.ID)
+	return tx.Model(&recovery).Update("used_at", time.Now()).Error
+}
+
+// setupTOTP 为尚未启用两步验证的用户生成待验证的密钥
+func setupTOTP(user *models.User) (gin.H, error) {
+	if user.TOTPEnabled {
+		return nil, errTOTPAlreadyEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(user).Update("totp_secret", secret).Error; err != nil {
+		return nil, err
+	}
+	return gin.H{"secret": secret, "otpauth_uri": totp.URI(totpIssuer(), user.Username, secret)}, nil
+}
+
+// enableTOTP 用待验证密钥校验第一个验证码，通过后启用两步验证并生成恢复码
+func enableTOTP(user *models.User, code string) ([]string, error) {
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(user, user.ID).Error; err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return errTOTPAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return errTOTPNotSetup
+		}
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return errSecondFactorInvalid
+		}
+		if err := tx.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// respondTwoFactorError 将两步验证相关错误转换为对应的HTTP响应
+func respondTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errMFATokenInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired mfa token"})
+	case errors.Is(err, errSecondFactorInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid verification code"})
+	case errors.Is(err, errTOTPAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+	case errors.Is(err, errTOTPNotSetup):
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication has not been set up"})
+	case errors.Is(err, errTOTPRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Two-factor authentication failed"})
+	}
+}
+
+// checkMFAThrottle 第二步验证与密码共用失败计数，防止暴力猜测验证码
+func checkMFAThrottle(c *gin.Context, user models.User) bool {
+	wait, locked, err := loginRetryAfter(usernameSubject(user.Username), ipSubject(c.ClientIP()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+	if wait > 0 {
+		respondLoginThrottled(c, wait, locked)
+		return false
+	}
+	return true
+}
+
+// LoginSecondFactorHandler 登录第二步：校验验证码或恢复码后签发正式令牌
+func LoginSecondFactorHandler(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, err := userFromMFAToken(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	if !checkMFAThrottle(c, user) {
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return verifySecondFactor(tx, &user, req.Code)
+	})
+	if errors.Is(err, errSecondFactorInvalid) {
+		recordLoginFailures(user.Username, c.ClientIP())
+	}
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	completeLogin(c, user, nil)
+}
+
+// LoginSetupTOTPHandler 角色要求两步验证但尚未设置时，在登录过程中生成密钥
+func LoginSetupTOTPHandler(c *gin.Context) {
+	var req MFASetupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, err := userFromMFAToken(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	resp, err := setupTOTP(&user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// LoginEnableTOTPHandler 在登录过程中启用两步验证，成功后签发正式令牌并返回恢复码
+func LoginEnableTOTPHandler(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, err := userFromMFAToken(req.MFAToken)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	if !checkMFAThrottle(c, user) {
+		return
+	}
+	codes, err := enableTOTP(&user, req.Code)
+	if errors.Is(err, errSecondFactorInvalid) {
+		recordLoginFailures(user.Username, c.ClientIP())
+	}
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	completeLogin(c, user, gin.H{"recovery_codes": codes})
+}
+
+// currentUser 查找当前登录用户，失败时直接写入错误响应
+func currentUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	userID, ok := currentUserID(c)
+	if !ok {
+		return user, false
+	}
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// GetTOTPStatusHandler 查看当前用户的两步验证状态
+func GetTOTPStatusHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var remaining int64
+	database.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&remaining)
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.TOTPEnabled,
+		"required":                 twoFactorRequired(user),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTOTPHandler 已登录用户生成待验证的两步验证密钥
+func SetupTOTPHandler(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	resp, err := setupTOTP(&user)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// EnableTOTPHandler 已登录用户校验第一个验证码并启用两步验证
+func EnableTOTPHandler(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	codes, err := enableTOTP(&user, req.Code)
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes})
+}
+
+// DisableTOTPHandler 关闭两步验证，角色要求两步验证时不允许关闭
+func DisableTOTPHandler(c *gin.Context) {
+	var req DisableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if twoFactorRequired(user) {
+			return errTOTPRequired
+		}
+		if err := verifySecondFactor(tx, &user, req.Code); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error
+	})
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodesHandler 校验验证码后重新生成恢复码
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var codes []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := verifySecondFactor(tx, &user, req.Code); err != nil {
+			return err
+		}
+		var err error
+		codes, err = generateRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		respondTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
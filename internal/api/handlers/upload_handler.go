@@ -4,12 +4,23 @@ import (
 	"fmt"
 	"net/http"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// UploadHandler 处理文件上传请求
+// allowedImageTypes 列出允许上传的图片扩展名及其内容类型
+// 上传目录与接口同源提供访问，不能保存 html、svg 等可以执行脚本的文件
+var allowedImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// UploadHandler 处理文件上传请求，只接受图片
 func UploadHandler(c *gin.Context) {
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -17,8 +28,27 @@ func UploadHandler(c *gin.Context) {
 		return
 	}
 
+	// 扩展名和文件内容都必须是允许的图片类型，内容类型按文件头判断，不信任客户端提供的值
+	extension := strings.ToLower(filepath.Ext(file.Filename))
+	contentType, ok := allowedImageTypes[extension]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only jpg, png, gif and webp images are allowed"})
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	head := make([]byte, 512)
+	n, _ := f.Read(head)
+	f.Close()
+	if http.DetectContentType(head[:n]) != contentType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File content does not match its extension"})
+		return
+	}
+
 	// 生成一个唯一的文件名以避免冲突
-	extension := filepath.Ext(file.Filename)
 	newFileName := uuid.New().String() + extension
 	
 	// 定义保存路径
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownRole = errors.New("role does not exist")
+	errLastAdmin   = errors.New("at least one enabled admin is required")
+)
+
+// CreateUserRequest 定义了管理员创建账号的请求结构，默认要求新账号首次登录后修改密码
+type CreateUserRequest struct {
+	Username           string `json:"username" binding:"required"`
+	Password           string `json:"password" binding:"required,min=8"`
+	Role               string `json:"role" binding:"required"`
+	MustChangePassword *bool  `json:"must_change_password"`
+}
+
+// UpdateUserRoleRequest 定义了修改账号角色的请求结构
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// SetUserPasswordRequest 定义了管理员设置临时密码的请求结构
+type SetUserPasswordRequest struct {
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+// ChangePasswordRequest 定义了用户修改自己密码的请求结构
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+func userResponse(u models.User) gin.H {
+	return gin.H{
+		"id":                   u.ID,
+		"username":             u.Username,
+		"role":                 u.Role,
+		"disabled":             u.Disabled,
+		"must_change_password": u.MustChangePassword,
+		"totp_enabled":         u.TOTPEnabled,
+		"last_login_at":        u.LastLoginAt,
+		"created_at":           u.CreatedAt,
+	}
+}
+
+// roleExists 检查角色是否已在角色表中定义
+func roleExists(tx *gorm.DB, name string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Role{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// ensureOtherAdmin 停用管理员或取消其管理员角色前，确认仍有其他可用的管理员
+func ensureOtherAdmin(tx *gorm.DB, user models.User) error {
+	if user.Role != models.RoleAdmin || user.Disabled {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.User{}).
+		Where("role = ? AND disabled = ? AND id <> ?", models.RoleAdmin, false, user.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errLastAdmin
+	}
+	return nil
+}
+
+// loadUserParam 按路径参数 id 查找账号，失败时直接写入错误响应
+func loadUserParam(c *gin.Context) (models.User, bool) {
+	var user models.User
+	id, ok := parseIDParam(c, "id")
+	if !ok {
+		return user, false
+	}
+	if err := database.DB.First(&user, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// permissionList 把权限集合转换为列表
+func permissionList(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for p := range set {
+		list = append(list, p)
+	}
+	return list
+}
+
+// requireAssignableRole 只能分配权限不超过自己的角色，失败时直接写入错误响应
+func requireAssignableRole(c *gin.Context, roleName string) bool {
+	perms, err := rolePermissions(database.DB, roleName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return false
+	}
+	return requireGrantable(c, permissionList(perms))
+}
+
+// loadManageableUser 按路径参数 id 查找账号，并要求该账号的权限不超过当前用户，
+// 避免只有账号管理权限的用户重置管理员密码或修改其角色；失败时直接写入错误响应
+func loadManageableUser(c *gin.Context) (models.User, bool) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return user, false
+	}
+	held, err := callerPermissions(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return user, false
+	}
+	target, err := rolePermissions(database.DB, user.Role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+		return user, false
+	}
+	if !coversPermissions(held, permissionList(target)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot manage an account with permissions you do not have"})
+		return user, false
+	}
+	return user, true
+}
+
+// rejectSelf 管理员不能停用自己或修改自己的角色，避免把自己锁在系统外
+func rejectSelf(c *gin.Context, user models.User) bool {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return true
+	}
+	if userID == user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot perform this action on your own account"})
+		return true
+	}
+	return false
+}
+
+// ListUsersHandler 列出账号及最近登录时间，支持按 role 和 disabled 过滤
+func ListUsersHandler(c *gin.Context) {
+	query := database.DB.Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if s := c.Query("disabled"); s != "" {
+		disabled, err := strconv.ParseBool(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid disabled"})
+			return
+		}
+		query = query.Where("disabled = ?", disabled)
+	}
+	var users []models.User
+	if err := query.Order("id").Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+	resp := make([]gin.H, 0, len(users))
+	for _, u := range users {
+		resp = append(resp, userResponse(u))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetUserHandler 获取单个账号信息
+func GetUserHandler(c *gin.Context) {
+	user, ok := loadUserParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, userResponse(user))
+}
+
+// CreateUserHandler 创建管理员、前台、财务等账号；教练账号请通过 /api/coaches 创建以同时生成教练资料。
+// 只能创建权限不超过自己的角色的账号
+func CreateUserHandler(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	if !requireAssignableRole(c, req.Role) {
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	user := models.User{
+		Username:           req.Username,
+		PasswordHash:       string(hashed),
+		Role:               req.Role,
+		MustChangePassword: req.MustChangePassword == nil || *req.MustChangePassword,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		exists, err := roleExists(tx, req.Role)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errUnknownRole
+		}
+		var count int64
+		if err := tx.Model(&models.User{}).Where("username = ?", req.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+		return tx.Create(&user).Error
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, userResponse(user))
+	case errors.Is(err, errUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role: " + req.Role})
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	}
+}
+
+// UpdateUserRoleHandler 修改账号角色，并撤销其会话使新角色立即生效
+func UpdateUserRoleHandler(c *gin.Context) {
+	user, ok := loadManageableUser(c)
+	if !ok || rejectSelf(c, user) {
+		return
+	}
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	if !requireAssignableRole(c, req.Role) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		exists, err := roleExists(tx, req.Role)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return errUnknownRole
+		}
+		if req.Role != models.RoleAdmin {
+			if err := ensureOtherAdmin(tx, user); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&user).Update("role", req.Role).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	respondUserUpdate(c, err, user.ID)
+}
+
+// DisableUserHandler 停用账号而不删除数据，同时撤销其所有会话
+func DisableUserHandler(c *gin.Context) {
+	user, ok := loadManageableUser(c)
+	if !ok || rejectSelf(c, user) {
+		return
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureOtherAdmin(tx, user); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("disabled", true).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	respondUserUpdate(c, err, user.ID)
+}
+
+// EnableUserHandler 重新启用被停用的账号
+func EnableUserHandler(c *gin.Context) {
+	user, ok := loadManageableUser(c)
+	if !ok {
+		return
+	}
+	err := database.DB.Model(&user).Update("disabled", false).Error
+	respondUserUpdate(c, err, user.ID)
+}
+
+// SetUserPasswordHandler 管理员为账号设置临时密码，用户下次登录后必须修改；原有会话全部撤销
+func SetUserPasswordHandler(c *gin.Context) {
+	user, ok := loadManageableUser(c)
+	if !ok {
+		return
+	}
+	var req SetUserPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"password_hash": string(hashed), "must_change_password": true}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err == nil {
+		clearLoginFailures(user.Username)
+	}
+	respondUserUpdate(c, err, user.ID)
+}
+
+// RequirePasswordChangeHandler 要求账号下次登录后修改密码，当前会话不受影响
+func RequirePasswordChangeHandler(c *gin.Context) {
+	user, ok := loadManageableUser(c)
+	if !ok {
+		return
+	}
+	err := database.DB.Model(&user).Update("must_change_password", true).Error
+	respondUserUpdate(c, err, user.ID)
+}
+
+// respondUserUpdate 根据修改结果返回最新的账号信息或对应的错误
+func respondUserUpdate(c *gin.Context, err error, userID uint) {
+	switch {
+	case err == nil:
+		var user models.User
+		if err := database.DB.First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+			return
+		}
+		c.JSON(http.StatusOK, userResponse(user))
+	case errors.Is(err, errUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+	case errors.Is(err, errLastAdmin):
+		c.JSON(http.StatusConflict, gin.H{"error": "At least one enabled admin account is required"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+	}
+}
+
+// ChangeOwnPasswordHandler 用户修改自己的密码；成功后撤销所有旧会话并返回新的令牌
+func ChangeOwnPasswordHandler(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	wait, locked, err := loginRetryAfter(usernameSubject(user.Username), ipSubject(c.ClientIP()))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if wait > 0 {
+		respondLoginThrottled(c, wait, locked)
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		recordLoginFailures(user.Username, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be different from the current password"})
+		return
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"password_hash": string(hashed), "must_change_password": false}).Error; err != nil {
+			return err
+		}
+		return revokeUserSessions(tx, user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	clearLoginFailures(user.Username)
+
+	user.MustChangePassword = false
+	resp, err := startSession(c, user)
+	if err != nil {
+		log.Printf("生成JWT失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateWaitlistRequest 定义了直接登记候补的请求结构，时间段字段与创建预约一致
+type CreateWaitlistRequest struct {
+	StudentName string          `json:"student_name"` // 提供 student_id 时可省略
+	StudentID   *uint           `json:"student_id"`
+	CoachID     uint            `json:"coach_id" binding:"required"`
+	Date        string          `json:"date" binding:"required"` // YYYY-MM-DD
+	Slots       []schedule.Slot `json:"slots"`
+	TimeSlots   string          `json:"time_slots"`
+	CourseID    *uint           `json:"course_id"`
+	StartTime   string          `json:"start_time"`
+	Contact     string          `json:"contact"`
+}
+
+func waitlistResponse(w models.WaitlistEntry) gin.H {
+	slots, _ := schedule.ParseSlots(w.TimeSlot)
+	return gin.H{
+		"id":                  w.ID,
+		"coach_id":            w.CoachID,
+		"date":                w.Date.Format("2006-01-02"),
+		"slots":               slots,
+		"time_slots":          w.TimeSlot,
+		"student_name":        w.StudentName,
+		"student_id":          w.StudentID,
+		"contact":             w.Contact,
+		"course_id":           w.CourseID,
+		"status":              w.Status,
+		"promoted_booking_id": w.PromotedBookingID,
+		"promoted_at":         w.PromotedAt,
+		"notified_at":         w.NotifiedAt,
+		"created_at":          w.CreatedAt,
+	}
+}
+
+func waitlistsResponse(entries []models.WaitlistEntry) []gin.H {
+	resp := make([]gin.H, 0, len(entries))
+	for _, w := range entries {
+		resp = append(resp, waitlistResponse(w))
+	}
+	return resp
+}
+
+// waitlistEntryFromBooking 将校验过的预约请求转换为候补记录
+func waitlistEntryFromBooking(b models.Booking, contact string, createdBy uint) models.WaitlistEntry {
+	return models.WaitlistEntry{
+		CoachID:     b.CoachID,
+		Date:        b.BookingDate,
+		TimeSlot:    b.TimeSlot,
+		StudentName: b.ClientInfo,
+		StudentID:   b.StudentID,
+		Contact:     contact,
+		CourseID:    b.CourseID,
+		Status:      models.WaitlistStatusWaiting,
+		CreatedBy:   createdBy,
+	}
+}
+
+// promoteWaitlist 在释放时间段的同一事务中，按登记顺序把现在可以安排的候补转为待确认预约
+func promoteWaitlist(tx *gorm.DB, coachID uint, date time.Time, changedBy uint) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("coach_id = ? AND date = ? AND status = ?", coachID, date.Format("2006-01-02"), models.WaitlistStatusWaiting).
+		Order("created_at, id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	var promoted []models.WaitlistEntry
+	for _, entry := range entries {
+		slots, err := schedule.ParseSlots(entry.TimeSlot)
+		if err != nil {
+			log.Printf("[Waitlist] entry %d has invalid time slots %q: %v", entry.ID, entry.TimeSlot, err)
+			continue
+		}
+		booking := models.Booking{
+			CoachID:     entry.CoachID,
+			BookingDate: entry.Date,
+			TimeSlot:    schedule.Format(slots),
+			ClientInfo:  entry.StudentName,
+			StudentID:   entry.StudentID,
+			Status:      models.BookingStatusPending,
+			CourseID:    entry.CourseID,
+			Slots:       toModelSlots(slots),
+		}
+		if entry.CourseID != nil {
+			var course models.Course
+			if err := tx.First(&course, *entry.CourseID).Error; err == nil {
+				booking.Price = course.Price
+				booking.Course = &course
+			}
+		}
+
+		// 使用保存点，单条候补无法安排时不影响整个事务
+		savepoint := fmt.Sprintf("waitlist_%d", entry.ID)
+		if err := tx.SavePoint(savepoint).Error; err != nil {
+			return nil, err
+		}
+		err = insertBooking(tx, &booking, changedBy)
+		if _, ok := bookingErrorMessage(err); ok {
+			if err := tx.RollbackTo(savepoint).Error; err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status":              models.WaitlistStatusPromoted,
+			"promoted_booking_id": booking.ID,
+			"promoted_at":         now,
+		}).Error; err != nil {
+			return nil, err
+		}
+		entry.Status = models.WaitlistStatusPromoted
+		entry.PromotedBookingID = &booking.ID
+		entry.PromotedAt = &now
+		log.Printf("[Waitlist] entry %d promoted to booking %d", entry.ID, booking.ID)
+		promoted = append(promoted, entry)
+	}
+	return promoted, nil
+}
+
+// ListWaitlistHandler 查询候补，可按 coach_id、date、status 过滤，教练只能查询自己的候补；
+// notified=false 可列出已转为预约但尚未通知学员的候补
+func ListWaitlistHandler(c *gin.Context) {
+	db := database.DB
+	if scoped, ok := scopedCoachID(c); ok {
+		db = db.Where("coach_id = ?", scoped)
+	} else if coachID := c.Query("coach_id"); coachID != "" {
+		db = db.Where("coach_id = ?", coachID)
+	}
+	if date := c.Query("date"); date != "" {
+		db = db.Where("date = ?", date)
+	}
+	if status := c.Query("status"); status != "" {
+		db = db.Where("status = ?", status)
+	}
+	if c.Query("notified") == "false" {
+		db = db.Where("status = ? AND notified_at IS NULL", models.WaitlistStatusPromoted)
+	}
+	var entries []models.WaitlistEntry
+	if err := db.Order("date, created_at, id").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve waitlist"})
+		return
+	}
+	c.JSON(http.StatusOK, waitlistsResponse(entries))
+}
+
+// CreateWaitlistHandler 直接登记候补
+func CreateWaitlistHandler(c *gin.Context) {
+	var req CreateWaitlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok || !requireOwnCoach(c, req.CoachID) {
+		return
+	}
+	booking, err := newBookingFromRequest(CreateBookingRequest{
+		StudentName: req.StudentName,
+		StudentID:   req.StudentID,
+		CoachID:     req.CoachID,
+		Date:        req.Date,
+		Slots:       req.Slots,
+		TimeSlots:   req.TimeSlots,
+		CourseID:    req.CourseID,
+		StartTime:   req.StartTime,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry := waitlistEntryFromBooking(booking, req.Contact, userID)
+	if err := database.DB.Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Joined waitlist successfully", "waitlist_entry": waitlistResponse(entry)})
+}
+
+// CancelWaitlistHandler 取消仍在等待中的候补
+func CancelWaitlistHandler(c *gin.Context) {
+	result := database.DB.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND status = ?", c.Param("id"), models.WaitlistStatusWaiting).
+		Update("status", models.WaitlistStatusCancelled)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel waitlist entry"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Waiting entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entry cancelled successfully"})
+}
+
+// MarkWaitlistNotifiedHandler 记录工作人员已通知学员候补转正
+func MarkWaitlistNotifiedHandler(c *gin.Context) {
+	result := database.DB.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND status = ?", c.Param("id"), models.WaitlistStatusPromoted).
+		Update("notified_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update waitlist entry"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promoted entry not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Waitlist entry marked as notified"})
+}
@@ -0,0 +1,230 @@
+package database
+
+import (
+	"classOrder-backend/internal/models"
+	"classOrder-backend/internal/schedule"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// dataMigration 描述一次只需执行一次的数据迁移
+type dataMigration struct {
+	Name string
+	Run  func(tx *gorm.DB) error
+}
+
+// dataMigrations 按顺序列出所有数据迁移，新增迁移请追加到末尾
+// 内置角色不在此列表中，由 ExecuteDataMigrations 每次启动时补充
+var dataMigrations = []dataMigration{
+	{Name: "booking_slots_from_time_slot", Run: migrateBookingSlots},
+	{Name: "link_bookings_to_students", Run: linkBookingStudents},
+}
+
+// ExecuteDataMigrations 先补充缺少的内置角色，再依次执行尚未记录在迁移历史中的数据迁移，每个迁移在独立事务中完成
+func ExecuteDataMigrations(db *gorm.DB) error {
+	// 角色权限为空时所有接口都会返回 403，因此不依赖任何数据迁移的结果
+	if err := db.Transaction(seedRoles); err != nil {
+		return fmt.Errorf("写入内置角色失败: %v", err)
+	}
+	for _, m := range dataMigrations {
+		var record MigrationRecord
+		if err := db.Where("name = ?", m.Name).First(&record).Error; err == nil {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&MigrationRecord{
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("执行数据迁移 %s 失败: %v", m.Name, err)
+		}
+		log.Printf("成功执行数据迁移: %s", m.Name)
+	}
+	return nil
+}
+
+// unparsedSlot 是无法解析 time_slot 的旧预约占用的时间段，整天占用以免被重复预约
+var unparsedSlot = schedule.Slot{Start: "00:00", End: "23:59"}
+
+// migrateBookingSlots 将旧的逗号分隔 time_slot 字符串拆分为 booking_slots 记录。
+// 存在无法解析的片段时，该预约保留原始 time_slot 并占用全天，同时在日志中列出，由管理员修正时间段
+func migrateBookingSlots(tx *gorm.DB) error {
+	var bookings []models.Booking
+	if err := tx.Preload("Slots").Find(&bookings).Error; err != nil {
+		return err
+	}
+	var invalidBookings []string
+	for _, b := range bookings {
+		if len(b.Slots) > 0 {
+			continue
+		}
+		slots, invalid := schedule.ParseSlotsLenient(b.TimeSlot)
+		if len(invalid) > 0 || len(slots) == 0 {
+			invalidBookings = append(invalidBookings, fmt.Sprintf("%d(%q)", b.ID, b.TimeSlot))
+			slot := models.BookingSlot{BookingID: b.ID, StartTime: unparsedSlot.Start, EndTime: unparsedSlot.End}
+			if err := tx.Create(&slot).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		for _, s := range slots {
+			slot := models.BookingSlot{BookingID: b.ID, StartTime: s.Start, EndTime: s.End}
+			if err := tx.Create(&slot).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Booking{}).Where("id = ?", b.ID).Update("time_slot", schedule.Format(slots)).Error; err != nil {
+			return err
+		}
+	}
+	if len(invalidBookings) > 0 {
+		log.Printf("警告: %d 条预约的时间段无法解析，已暂时占用全天，请修正时间段: %s", len(invalidBookings), strings.Join(invalidBookings, ", "))
+	}
+	return nil
+}
+
+// builtInRoles 是初始的角色及权限，之后可以通过 /api/roles 调整
+var builtInRoles = []struct {
+	Name        string
+	Description string
+	Permissions []string
+}{
+	{models.RoleAdmin, "管理员", []string{models.PermissionAll}},
+	{models.RoleCoach, "教练，只能管理自己的预约", []string{
+		models.PermissionBookingsRead, models.PermissionBookingsWrite,
+	}},
+	{models.RoleHeadCoach, "总教练，可以查看所有教练的课表", []string{
+		models.PermissionBookingsRead, models.PermissionBookingsReadAll, models.PermissionBookingsWrite,
+	}},
+	{models.RoleFrontDesk, "前台，可以为任意教练预约，不能管理教练", []string{
+		models.PermissionBookingsRead, models.PermissionBookingsReadAll,
+		models.PermissionBookingsWrite, models.PermissionBookingsWriteAll,
+	}},
+	{models.RoleFinance, "财务，只读", []string{
+		models.PermissionBookingsRead, models.PermissionBookingsReadAll, models.PermissionFinanceRead,
+	}},
+	{models.RoleStudent, "学员，自助预约和取消自己的课程", []string{models.PermissionBookingsSelf}},
+}
+
+// seedRoles 写入内置角色和权限，已存在的角色保持不变
+func seedRoles(tx *gorm.DB) error {
+	for _, r := range builtInRoles {
+		var count int64
+		if err := tx.Model(&models.Role{}).Where("name = ?", r.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		role := models.Role{Name: r.Name, Description: r.Description, BuiltIn: true}
+		for _, p := range r.Permissions {
+			role.Permissions = append(role.Permissions, models.RolePermission{Permission: p})
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clientPhonePattern 匹配 client_info 中夹带的手机号或电话
+var clientPhonePattern = regexp.MustCompile(`\+?\d[\d-]{5,18}\d`)
+
+// splitClientInfo 从 client_info 中拆出姓名和电话，例如 "张三 138-0013-8000" 或 "李四（13800138000）"
+func splitClientInfo(info string) (string, string) {
+	phone := ""
+	if m := clientPhonePattern.FindString(info); m != "" {
+		phone = strings.ReplaceAll(m, "-", "")
+		info = strings.Replace(info, m, " ", 1)
+	}
+	name := strings.Trim(info, " \t,，/、;；:：()（）-")
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		name = phone
+	}
+	return name, phone
+}
+
+// matchOrCreateStudent 按电话（含监护人电话）查找唯一的学员，找不到时新建；
+// 匹配到多个学员时无法判断是谁，返回 nil
+func matchOrCreateStudent(tx *gorm.DB, name, phone string) (*uint, error) {
+	var students []models.Student
+	if err := tx.Select("id").Where("phone = ? OR guardian_phone = ?", phone, phone).Limit(2).Find(&students).Error; err != nil {
+		return nil, err
+	}
+	switch len(students) {
+	case 0:
+		student := models.Student{Name: name, Phone: phone}
+		if r := []rune(student.Name); len(r) > 100 {
+			student.Name = string(r[:100])
+		}
+		if err := tx.Create(&student).Error; err != nil {
+			return nil, err
+		}
+		return &student.ID, nil
+	case 1:
+		return &students[0].ID, nil
+	default:
+		return nil, nil
+	}
+}
+
+// linkBookingStudents 把 client_info 中包含电话的已有预约按电话关联到学员，找不到时新建学员。
+// 只有姓名的预约不做关联：同名的可能是不同学员，合并后会共用课程记录和进度，
+// 这些预约和匹配到多个学员的预约一样记录在日志中，留给工作人员手动关联
+func linkBookingStudents(tx *gorm.DB) error {
+	var bookings []models.Booking
+	if err := tx.Select("id", "client_info").
+		Where("student_id IS NULL AND client_info IS NOT NULL AND client_info <> ''").
+		Order("id").Find(&bookings).Error; err != nil {
+		return err
+	}
+	matched := make(map[string]*uint)
+	linked := 0
+	var nameOnly, ambiguous []string
+	for _, b := range bookings {
+		name, phone := splitClientInfo(b.ClientInfo)
+		if name == "" {
+			continue
+		}
+		if phone == "" {
+			nameOnly = append(nameOnly, fmt.Sprintf("%d(%q)", b.ID, b.ClientInfo))
+			continue
+		}
+		studentID, seen := matched[phone]
+		if !seen {
+			var err error
+			if studentID, err = matchOrCreateStudent(tx, name, phone); err != nil {
+				return err
+			}
+			matched[phone] = studentID
+		}
+		if studentID == nil {
+			ambiguous = append(ambiguous, fmt.Sprintf("%d(%q)", b.ID, b.ClientInfo))
+			continue
+		}
+		if err := tx.Model(&models.Booking{}).Where("id = ?", b.ID).Update("student_id", *studentID).Error; err != nil {
+			return err
+		}
+		linked++
+	}
+	if len(nameOnly) > 0 {
+		log.Printf("警告: %d 条预约只有学员姓名，未关联，请手动关联: %s", len(nameOnly), strings.Join(nameOnly, ", "))
+	}
+	if len(ambiguous) > 0 {
+		log.Printf("警告: %d 条预约的电话匹配到多个学员，未关联，请手动关联: %s", len(ambiguous), strings.Join(ambiguous, ", "))
+	}
+	log.Printf("预约关联学员完成: 关联 %d 条，跳过 %d 条", linked, len(nameOnly)+len(ambiguous))
+	return nil
+}
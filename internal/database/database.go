@@ -80,6 +80,41 @@ func ExecuteMigrations(db *gorm.DB) error {
 	return nil
 }
 
+// AutoMigrate 根据模型创建或更新表结构
+func AutoMigrate(db *gorm.DB) error {
+	return db.Migrator().AutoMigrate(
+		&models.User{},
+		&models.Role{},
+		&models.RolePermission{},
+		&models.Student{},
+		&models.LessonReport{},
+		&models.CoachReview{},
+		&models.CoachCertification{},
+		&models.CoachSpecialty{},
+		&models.CoachLanguage{},
+		&models.CalendarFeed{},
+		&models.LessonReportPhoto{},
+		&models.AuthSession{},
+		&models.RefreshToken{},
+		&models.LoginThrottle{},
+		&models.PasswordResetToken{},
+		&models.RecoveryCode{},
+		&models.Coach{},
+		&models.Course{},
+		&models.BookingSeries{},
+		&models.Booking{},
+		&models.CoachDayLock{},
+		&models.BookingSlot{},
+		&models.CoachWorkingHour{},
+		&models.CoachDateOverride{},
+		&models.Blackout{},
+		&models.BookingStatusChange{},
+		&models.GroupSession{},
+		&models.GroupEnrollment{},
+		&models.WaitlistEntry{},
+	)
+}
+
 // InitDB 初始化数据库连接并执行自动迁移
 func InitDB() {
 	var err error
@@ -113,14 +148,16 @@ func InitDB() {
 	log.Println("自定义迁移成功完成。")
 
 	// 自动迁移模型（不处理外键约束）
-	if err := DB.Migrator().AutoMigrate(
-		&models.User{},
-		&models.Coach{},
-		&models.Booking{},
-	); err != nil {
+	if err := AutoMigrate(DB); err != nil {
 		log.Printf("警告: 自动迁移表失败: %v", err)
 		return
 	}
 
 	log.Println("数据库迁移检查成功。")
+
+	// 最后执行依赖新表结构的数据迁移
+	// 数据迁移失败时继续启动会导致角色权限或预约时间段缺失，直接终止
+	if err := ExecuteDataMigrations(DB); err != nil {
+		log.Fatalf("数据迁移失败: %v", err)
+	}
 }
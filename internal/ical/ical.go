@@ -0,0 +1,238 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 事件状态，对应 RFC 5545 的 STATUS 属性
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event 是日历中的一个 VEVENT
+type Event struct {
+	UID          string
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	LastModified time.Time
+	Sequence     int  // 事件的修订次数，每次修改后递增，日历客户端据此判断是否需要更新
+	AllDay       bool // 全天事件，只有日期没有时间，仅在解析时设置
+}
+
+// Calendar 是一份 VCALENDAR 订阅内容
+type Calendar struct {
+	Name   string // 订阅时显示的日历名称
+	Events []Event
+}
+
+const timeFormat = "20060102T150405Z"
+
+// escapeText 按 RFC 5545 转义 TEXT 类型的值
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine 把超过 75 字节的内容行折行，不会截断多字节字符
+func foldLine(line string) string {
+	if len(line) <= 75 {
+		return line + "\r\n"
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // 续行开头的空格占一个字节
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// Write 把日历写为 text/calendar 格式，时间统一转换为 UTC
+func Write(w io.Writer, cal Calendar) error {
+	now := time.Now().UTC().Format(timeFormat)
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//ClassOrder//Booking Calendar//ZH",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	if cal.Name != "" {
+		lines = append(lines, "X-WR-CALNAME:"+escapeText(cal.Name))
+	}
+	for _, e := range cal.Events {
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			"UID:"+e.UID,
+			"DTSTAMP:"+now,
+			"DTSTART:"+e.Start.UTC().Format(timeFormat),
+			"DTEND:"+e.End.UTC().Format(timeFormat),
+			"SUMMARY:"+escapeText(e.Summary),
+			"SEQUENCE:"+strconv.Itoa(e.Sequence),
+		)
+		if e.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escapeText(e.Description))
+		}
+		if e.Status != "" {
+			lines = append(lines, "STATUS:"+e.Status)
+		}
+		if !e.LastModified.IsZero() {
+			lines = append(lines, "LAST-MODIFIED:"+e.LastModified.UTC().Format(timeFormat))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldLine(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unescapeText 是 escapeText 的逆操作
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// unfold 读取全部内容行，合并以空格或制表符开头的续行
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty 把内容行拆分为属性名、参数和值，参数值中的冒号需要用引号括起来
+func splitProperty(line string) (string, map[string]string, string, bool) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", false
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+// parseTime 解析 DATE-TIME 或 DATE 值：带 Z 的为 UTC，带 TZID 的按该时区，其余按 loc 解释
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(timeFormat, value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// Parse 读取 iCalendar 内容中的全部 VEVENT；没有时区信息的时间按 loc 解释。
+// 缺少开始时间或时间格式错误的事件会返回错误，错误信息中带有事件序号（从1开始）
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	var current *Event
+	var hasEnd bool
+	for _, line := range lines {
+		name, params, value, ok := splitProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &Event{}
+			hasEnd = false
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current == nil {
+				continue
+			}
+			n := len(events) + 1
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("event %d: missing DTSTART", n)
+			}
+			if !hasEnd {
+				current.End = current.Start
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case name == "DESCRIPTION":
+			current.Description = unescapeText(value)
+		case name == "STATUS":
+			current.Status = strings.ToUpper(value)
+		case name == "DTSTART" || name == "DTEND":
+			t, allDay, err := parseTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: invalid %s %q", len(events)+1, name, value)
+			}
+			if name == "DTSTART" {
+				current.Start, current.AllDay = t, allDay
+			} else {
+				current.End, hasEnd = t, true
+			}
+		}
+	}
+	if current != nil {
+		return nil, errors.New("unterminated VEVENT")
+	}
+	return events, nil
+}
@@ -0,0 +1,252 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`a\b`, `a\\b`},
+		{"a;b,c", `a\;b\,c`},
+		{"line1\nline2", `line1\nline2`},
+		{"line1\r\nline2", `line1\nline2`},
+		{"学员：张三，李四", "学员：张三，李四"}, // 全角标点不需要转义
+	}
+	for _, tt := range tests {
+		if got := escapeText(tt.in); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFoldLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:short"},
+		{"exactly 75", "SUMMARY:" + strings.Repeat("a", 67)},
+		{"ascii", "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{"multibyte", "SUMMARY:" + strings.Repeat("滑雪课程", 30)},
+		{"mixed", "DESCRIPTION:a" + strings.Repeat("教练：张三 ", 25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := foldLine(tt.line)
+			if !strings.HasSuffix(folded, "\r\n") {
+				t.Fatalf("folded line does not end with CRLF: %q", folded)
+			}
+			physical := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			var unfolded strings.Builder
+			for i, p := range physical {
+				if len(p) > 75 {
+					t.Errorf("line %d is %d octets long", i, len(p))
+				}
+				if !utf8.ValidString(p) {
+					t.Errorf("line %d splits a multibyte character: %q", i, p)
+				}
+				if i > 0 {
+					if !strings.HasPrefix(p, " ") {
+						t.Errorf("continuation line %d does not start with a space", i)
+					}
+					p = p[1:]
+				}
+				unfolded.WriteString(p)
+			}
+			if unfolded.String() != tt.line {
+				t.Errorf("unfolded = %q, want %q", unfolded.String(), tt.line)
+			}
+			if len(tt.line) <= 75 && len(physical) != 1 {
+				t.Errorf("line of %d octets was folded", len(tt.line))
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	cal := Calendar{
+		Name: "张三的课程",
+		Events: []Event{{
+			UID:          "booking-1-0900@classorder",
+			Start:        time.Date(2025, 1, 4, 9, 0, 0, 0, loc),
+			End:          time.Date(2025, 1, 4, 10, 30, 0, 0, loc),
+			Summary:      "李四 · 单板入门",
+			Description:  "学员：李四\n备注：自带雪板; 需要头盔, 护具",
+			Status:       StatusCancelled,
+			LastModified: time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC),
+			Sequence:     3,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, cal); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:张三的课程\r\n",
+		"UID:booking-1-0900@classorder\r\n",
+		"DTSTART:20250104T010000Z\r\n",
+		"DTEND:20250104T023000Z\r\n",
+		"SUMMARY:李四 · 单板入门\r\n",
+		`DESCRIPTION:学员：李四\n备注：自带雪板\; 需要头盔\, 护具` + "\r\n",
+		"STATUS:CANCELLED\r\n",
+		"SEQUENCE:3\r\n",
+		"LAST-MODIFIED:20250102T120000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Errorf("output contains bare LF")
+	}
+}
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	loc := time.FixedZone("CST", 8*3600)
+	events := []Event{
+		{
+			UID:         "booking-1-0900@classorder",
+			Start:       time.Date(2025, 1, 4, 9, 0, 0, 0, loc),
+			End:         time.Date(2025, 1, 4, 10, 30, 0, 0, loc),
+			Summary:     `王五\张三; 双板, 进阶`,
+			Description: "学员：王五\n" + strings.Repeat("课程说明：注意保暖，带好雪镜。", 10),
+			Status:      StatusConfirmed,
+		},
+		{
+			UID:     "booking-2-1400@classorder",
+			Start:   time.Date(2025, 1, 5, 14, 0, 0, 0, loc),
+			End:     time.Date(2025, 1, 5, 15, 0, 0, 0, loc),
+			Summary: strings.Repeat("很长的学员姓名", 20),
+			Status:  StatusTentative,
+		},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Calendar{Name: "全部课程", Events: events}); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(&buf, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed) != len(events) {
+		t.Fatalf("parsed %d events, want %d", len(parsed), len(events))
+	}
+	for i, want := range events {
+		got := parsed[i]
+		if got.UID != want.UID || got.Summary != want.Summary || got.Description != want.Description || got.Status != want.Status {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.AllDay {
+			t.Errorf("event %d time = %s-%s (all day %v), want %s-%s", i, got.Start, got.End, got.AllDay, want.Start, want.End)
+		}
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("time zone database is not available")
+	}
+	local := time.FixedZone("local", -5*3600)
+	tests := []struct {
+		name       string
+		start, end string
+		wantStart  time.Time
+		wantEnd    time.Time
+		allDay     bool
+	}{
+		{"utc", "DTSTART:20250104T010000Z", "DTEND:20250104T020000Z",
+			time.Date(2025, 1, 4, 1, 0, 0, 0, time.UTC), time.Date(2025, 1, 4, 2, 0, 0, 0, time.UTC), false},
+		{"tzid", "DTSTART;TZID=Asia/Shanghai:20250104T090000", "DTEND;TZID=\"Asia/Shanghai\":20250104T100000",
+			time.Date(2025, 1, 4, 9, 0, 0, 0, shanghai), time.Date(2025, 1, 4, 10, 0, 0, 0, shanghai), false},
+		{"floating uses loc", "DTSTART:20250104T090000", "DTEND:20250104T100000",
+			time.Date(2025, 1, 4, 9, 0, 0, 0, local), time.Date(2025, 1, 4, 10, 0, 0, 0, local), false},
+		{"unknown tzid uses loc", "DTSTART;TZID=Mars/Base:20250104T090000", "DTEND;TZID=Mars/Base:20250104T100000",
+			time.Date(2025, 1, 4, 9, 0, 0, 0, local), time.Date(2025, 1, 4, 10, 0, 0, 0, local), false},
+		{"date value", "DTSTART;VALUE=DATE:20250104", "DTEND;VALUE=DATE:20250105",
+			time.Date(2025, 1, 4, 0, 0, 0, 0, local), time.Date(2025, 1, 5, 0, 0, 0, 0, local), true},
+		{"missing end", "DTSTART:20250104T010000Z", "",
+			time.Date(2025, 1, 4, 1, 0, 0, 0, time.UTC), time.Date(2025, 1, 4, 1, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\n" + tt.start + "\r\n"
+			if tt.end != "" {
+				src += tt.end + "\r\n"
+			}
+			src += "END:VEVENT\r\nEND:VCALENDAR\r\n"
+			events, err := Parse(strings.NewReader(src), local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("parsed %d events, want 1", len(events))
+			}
+			e := events[0]
+			if !e.Start.Equal(tt.wantStart) || !e.End.Equal(tt.wantEnd) || e.AllDay != tt.allDay {
+				t.Errorf("got %s-%s all day %v, want %s-%s all day %v", e.Start, e.End, e.AllDay, tt.wantStart, tt.wantEnd, tt.allDay)
+			}
+		})
+	}
+}
+
+func TestParseUnfoldsAndUnescapes(t *testing.T) {
+	// 续行可以用空格或制表符开头，行尾可以只有 LF
+	src := "BEGIN:VEVENT\nDTSTART:20250104T010000Z\r\nSUMMARY:滑雪\r\n 课程\\, 初\n\t级\r\nDESCRIPTION:第一行\\n第二行\\N第三行\\;\\\\\r\nstatus:cancelled\r\nEND:VEVENT\r\n"
+	events, err := Parse(strings.NewReader(src), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("parsed %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Summary != "滑雪课程, 初级" {
+		t.Errorf("Summary = %q", e.Summary)
+	}
+	if e.Description != "第一行\n第二行\n第三行;\\" {
+		t.Errorf("Description = %q", e.Description)
+	}
+	if e.Status != StatusCancelled {
+		t.Errorf("Status = %q", e.Status)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"unterminated", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20250104T010000Z\r\nEND:VCALENDAR\r\n", "unterminated VEVENT"},
+		{"missing start", "BEGIN:VEVENT\r\nSUMMARY:a\r\nEND:VEVENT\r\n", "event 1: missing DTSTART"},
+		{"invalid time", "BEGIN:VEVENT\r\nDTSTART:20250104T010000Z\r\nEND:VEVENT\r\nBEGIN:VEVENT\r\nDTSTART:2025-01-04 09:00\r\nEND:VEVENT\r\n", "event 2: invalid DTSTART"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.src), time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIgnoresOtherComponents(t *testing.T) {
+	src := "BEGIN:VCALENDAR\r\nSUMMARY:outside\r\nEND:VEVENT\r\nBEGIN:VTIMEZONE\r\nDTSTART:19700101T000000\r\nEND:VTIMEZONE\r\nnot a property\r\nEND:VCALENDAR\r\n"
+	events, err := Parse(strings.NewReader(src), time.UTC)
+	if err != nil || len(events) != 0 {
+		t.Errorf("Parse = %v, %v; want no events", events, err)
+	}
+}
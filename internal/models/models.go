@@ -6,32 +6,464 @@ import (
 
 // User 对应于 'users' 表
 type User struct {
-	ID           uint      `gorm:"primaryKey"`
-	Username     string    `gorm:"type:varchar(255);not null;unique"`
-	PasswordHash string    `gorm:"type:varchar(255);not null"`
-	Role         string    `gorm:"type:varchar(50);not null"` // 'admin' 或 'coach'
-	CreatedAt    time.Time
+	ID                 uint       `gorm:"primaryKey"`
+	Username           string     `gorm:"type:varchar(255);not null;unique"`
+	PasswordHash       string     `gorm:"type:varchar(255);not null"`
+	Role               string     `gorm:"type:varchar(50);not null"`                  // 角色名，见 Role 表
+	TOTPSecret         string     `gorm:"column:totp_secret;type:varchar(64)"`        // base32 编码，启用前为待验证的密钥
+	TOTPEnabled        bool       `gorm:"column:totp_enabled;not null;default:false"` // 是否已启用两步验证
+	TOTPLastStep       int64      `gorm:"column:totp_last_step;not null;default:0"`   // 最近一次使用的时间步，防止验证码重放
+	Disabled           bool       `gorm:"not null;default:false"`                     // 停用的账号不能登录，数据保留
+	MustChangePassword bool       `gorm:"not null;default:false"`                     // 下次登录后必须先修改密码
+	LastLoginAt        *time.Time // 最近一次登录成功的时间
+	CreatedAt          time.Time
 	// Coach        Coach     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"` // 移除递归引用
 }
 
+// Student 对应于 'students' 表，学员自助注册时关联登录账号，工作人员录入的学员可以没有账号
+type Student struct {
+	ID            uint       `gorm:"primaryKey"`
+	UserID        *uint      `gorm:"uniqueIndex"` // 学员的登录账号，可为空
+	Name          string     `gorm:"type:varchar(100);not null;index"`
+	Phone         string     `gorm:"type:varchar(32);index"`
+	Email         string     `gorm:"type:varchar(255);index"`
+	BirthDate     *time.Time `gorm:"type:date"`
+	Discipline    string     `gorm:"type:varchar(20)"` // 见 Discipline* 常量
+	Level         string     `gorm:"type:varchar(50)"` // 技术等级，例如 初级、中级、高级
+	GuardianName  string     `gorm:"type:varchar(100)"`
+	GuardianPhone string     `gorm:"type:varchar(32);index"` // 未成年学员的监护人联系方式
+	Notes         string     `gorm:"type:text"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// 滑雪项目
+const (
+	DisciplineSki       = "ski"
+	DisciplineSnowboard = "snowboard"
+	DisciplineBoth      = "both"
+)
+
+// IsValidDiscipline 判断项目是否有效
+func IsValidDiscipline(d string) bool {
+	return d == DisciplineSki || d == DisciplineSnowboard || d == DisciplineBoth
+}
+
+// LessonReport 对应于 'lesson_reports' 表，教练在课后为已完成的预约填写的课程报告，每个预约一份
+type LessonReport struct {
+	ID        uint   `gorm:"primaryKey"`
+	BookingID uint   `gorm:"not null;uniqueIndex"`
+	CoachID   uint   `gorm:"not null;index"`
+	StudentID *uint  `gorm:"index"`             // 来自预约，便于汇总学员的进度
+	Skills    string `gorm:"type:varchar(500)"` // 练习的技术要点，逗号分隔
+	Level     string `gorm:"type:varchar(50)"`  // 课后评定的技术等级
+	Notes     string `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Photos    []LessonReportPhoto `gorm:"foreignKey:ReportID;constraint:OnDelete:CASCADE;"`
+	Booking   *Booking            `gorm:"foreignKey:BookingID"`
+}
+
+// LessonReportPhoto 对应于 'lesson_report_photos' 表，URL 来自上传接口
+type LessonReportPhoto struct {
+	ID        uint   `gorm:"primaryKey"`
+	ReportID  uint   `gorm:"not null;index"`
+	URL       string `gorm:"column:url;type:varchar(255);not null"`
+	CreatedAt time.Time
+}
+
+// CoachReview 对应于 'coach_reviews' 表，学员对已完成课程的评价，每个预约一条
+type CoachReview struct {
+	ID          uint   `gorm:"primaryKey"`
+	BookingID   uint   `gorm:"not null;uniqueIndex"`
+	CoachID     uint   `gorm:"not null;index"`
+	StudentID   uint   `gorm:"not null;index"`
+	Rating      int    `gorm:"not null"` // 1-5 分
+	Comment     string `gorm:"type:text"`
+	Status      string `gorm:"type:varchar(20);not null;default:'pending';index"` // 见 ReviewStatus* 常量
+	ModeratedBy *uint  // 最后审核的管理员
+	ModeratedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// 评价审核状态，只有 approved 的评价公开展示并计入评分
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusHidden   = "hidden"
+)
+
+// Role 对应于 'roles' 表，User.Role 保存角色名
+type Role struct {
+	Name        string `gorm:"type:varchar(50);primaryKey"`
+	Description string `gorm:"type:varchar(255)"`
+	BuiltIn     bool   `gorm:"not null;default:false"` // 内置角色不能删除
+	CreatedAt   time.Time
+	Permissions []RolePermission `gorm:"foreignKey:RoleName;constraint:OnDelete:CASCADE;"`
+}
+
+// RolePermission 对应于 'role_permissions' 表，每行是角色拥有的一项权限
+type RolePermission struct {
+	RoleName   string `gorm:"type:varchar(50);primaryKey"`
+	Permission string `gorm:"type:varchar(50);primaryKey"`
+}
+
+// 内置角色
+const (
+	RoleAdmin     = "admin"
+	RoleCoach     = "coach"
+	RoleHeadCoach = "head_coach"
+	RoleFrontDesk = "front_desk"
+	RoleFinance   = "finance"
+	RoleStudent   = "student"
+)
+
+// 权限，格式为 资源:操作
+const (
+	PermissionAll              = "*"                  // 全部权限
+	PermissionCoachesManage    = "coaches:manage"     // 创建、修改、删除教练及其工作时间
+	PermissionBookingsRead     = "bookings:read"      // 查看自己的预约
+	PermissionBookingsReadAll  = "bookings:read_all"  // 查看所有教练的预约
+	PermissionBookingsWrite    = "bookings:write"     // 创建、修改、取消自己的预约
+	PermissionBookingsWriteAll = "bookings:write_all" // 为任意教练创建、修改、取消预约
+	PermissionCoursesManage    = "courses:manage"     // 管理课程目录
+	PermissionBlackoutsManage  = "blackouts:manage"   // 管理休假与停课
+	PermissionSecurityManage   = "security:manage"    // 解除登录锁定、撤销会话
+	PermissionRolesManage      = "roles:manage"       // 管理角色与权限
+	PermissionUsersManage      = "users:manage"       // 创建、停用账号，分配角色
+	PermissionFinanceRead      = "finance:read"       // 查看价格与收入
+	PermissionBookingsSelf     = "bookings:self"      // 学员预约、查看和取消自己的课程
+	PermissionReviewsModerate  = "reviews:moderate"   // 审核学员评价
+)
+
+// AllPermissions 列出可以分配给角色的全部权限
+var AllPermissions = []string{
+	PermissionAll,
+	PermissionCoachesManage,
+	PermissionBookingsRead,
+	PermissionBookingsReadAll,
+	PermissionBookingsWrite,
+	PermissionBookingsWriteAll,
+	PermissionCoursesManage,
+	PermissionBlackoutsManage,
+	PermissionSecurityManage,
+	PermissionRolesManage,
+	PermissionUsersManage,
+	PermissionFinanceRead,
+	PermissionBookingsSelf,
+	PermissionReviewsModerate,
+}
+
+// IsValidPermission 判断权限名是否有效
+func IsValidPermission(p string) bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthSession 对应于 'auth_sessions' 表，每次登录创建一个会话
+// 访问令牌通过 sid 声明关联会话，会话被撤销后其访问令牌和刷新令牌都会失效
+type AuthSession struct {
+	ID        string `gorm:"type:char(32);primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	UserAgent string `gorm:"type:varchar(255)"`
+	IP        string `gorm:"type:varchar(64)"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RefreshToken 对应于 'refresh_tokens' 表，只保存令牌的 SHA-256 摘要
+// 每次刷新都会换发新令牌，已使用过的令牌再次出现时视为泄露并撤销整个会话
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	SessionID string    `gorm:"type:char(32);not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// PasswordResetToken 对应于 'password_reset_tokens' 表，只保存令牌的 SHA-256 摘要，令牌只能使用一次
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RecoveryCode 对应于 'recovery_codes' 表，两步验证设备丢失时使用的一次性恢复码，只保存摘要
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"type:char(64);not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// LoginThrottle 对应于 'login_throttles' 表，按用户名或IP记录连续登录失败次数
+type LoginThrottle struct {
+	Subject      string     `gorm:"type:varchar(255);primaryKey"` // user:<用户名> 或 ip:<地址>
+	Failures     int        `gorm:"not null;default:0"`
+	BlockedUntil *time.Time // 在此之前拒绝登录，用于退避和锁定
+	Locked       bool       `gorm:"not null;default:false"` // 失败次数达到阈值而被锁定
+	LastFailedAt time.Time
+}
+
 // Coach 对应于 'coaches' 表
 type Coach struct {
-	ID          uint      `gorm:"primaryKey"`
-	UserID      uint      `gorm:"not null;unique"`
-	Name        string    `gorm:"type:varchar(255);not null"`
-	Description string    `gorm:"type:text"`
-	AvatarURL   string    `gorm:"type:varchar(255)"`
-	CreatedAt   time.Time
-	Bookings    []Booking `gorm:"foreignKey:CoachID;constraint:OnDelete:CASCADE;"` // 一对多关系
-	User        User      `gorm:"foreignKey:UserID"` // 新增字段
+	ID             uint   `gorm:"primaryKey"`
+	UserID         uint   `gorm:"not null;unique"`
+	Name           string `gorm:"type:varchar(255);not null"`
+	Description    string `gorm:"type:text"`
+	AvatarURL      string `gorm:"type:varchar(255)"`
+	Discipline     string `gorm:"type:varchar(20);index"` // 教授的项目，见 Discipline* 常量
+	CreatedAt      time.Time
+	Bookings       []Booking            `gorm:"foreignKey:CoachID;constraint:OnDelete:CASCADE;"` // 一对多关系
+	User           User                 `gorm:"foreignKey:UserID"`                               // 新增字段
+	Certifications []CoachCertification `gorm:"foreignKey:CoachID;constraint:OnDelete:CASCADE;"`
+	Specialties    []CoachSpecialty     `gorm:"foreignKey:CoachID;constraint:OnDelete:CASCADE;"`
+	Languages      []CoachLanguage      `gorm:"foreignKey:CoachID;constraint:OnDelete:CASCADE;"`
+}
+
+// CoachCertification 对应于 'coach_certifications' 表，记录教练持有的资格证书
+type CoachCertification struct {
+	ID         uint       `gorm:"primaryKey"`
+	CoachID    uint       `gorm:"not null;index"`
+	Body       string     `gorm:"type:varchar(50);not null;index"` // 颁证机构，例如 PSIA、CASI、奥地利、国职
+	Level      string     `gorm:"type:varchar(50)"`                // 等级，例如 二级、Level 3
+	Discipline string     `gorm:"type:varchar(20)"`                // 证书对应的项目，可为空
+	ExpiresAt  *time.Time `gorm:"type:date"`                       // 有效期，为空表示长期有效
+	CreatedAt  time.Time
+}
+
+// CoachSpecialty 对应于 'coach_specialties' 表，教练的专长标签，例如 kids、freestyle、carving
+type CoachSpecialty struct {
+	CoachID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Tag     string `gorm:"type:varchar(50);primaryKey;index"`
+}
+
+// CoachLanguage 对应于 'coach_languages' 表，教练授课使用的语言，例如 zh、en、ja
+type CoachLanguage struct {
+	CoachID  uint   `gorm:"primaryKey;autoIncrement:false"`
+	Language string `gorm:"type:varchar(20);primaryKey;index"`
+}
+
+// CalendarFeed 对应于 'calendar_feeds' 表，日历订阅链接中的令牌只保存摘要；
+// CoachID 为空表示包含全部教练课程的全校订阅
+type CalendarFeed struct {
+	ID        uint   `gorm:"primaryKey"`
+	CoachID   *uint  `gorm:"uniqueIndex"`
+	TokenHash string `gorm:"type:char(64);not null;uniqueIndex"`
+	CreatedBy uint   `gorm:"not null"`
+	CreatedAt time.Time
 }
 
 // Booking 对应于 'bookings' 表
 type Booking struct {
+	ID              uint      `gorm:"primaryKey"`
+	CoachID         uint      `gorm:"not null;index:idx_bookings_coach_date"`
+	BookingDate     time.Time `gorm:"type:date;not null;index:idx_bookings_coach_date"`
+	TimeSlot        string    `gorm:"type:varchar(255);not null"` // 由 Slots 生成的展示字符串，兼容旧版前端
+	ClientInfo      string    `gorm:"type:varchar(255)"`
+	Status          string    `gorm:"type:varchar(20);not null;default:'confirmed';index"` // 见 BookingStatus* 常量
+	StatusReason    string    `gorm:"type:varchar(255)"`                                   // 最近一次状态变更的原因，例如取消原因
+	StatusChangedAt *time.Time
+	CourseID        *uint `gorm:"index"` // 关联的课程，可为空
+	Price           int   // 预约时的课程价格快照（元）
+	SeriesID        *uint `gorm:"index"`                  // 所属的重复预约系列，可为空
+	SeriesException bool  `gorm:"not null;default:false"` // 单独修改过的系列预约，整体修改系列时跳过
+	StudentID       *uint `gorm:"index"`                  // 关联的学员，ClientInfo 仍保存学员姓名
+	Sequence        int   `gorm:"not null;default:0"`     // 修改次数，用作日历订阅中的 SEQUENCE
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Slots           []BookingSlot `gorm:"foreignKey:BookingID;constraint:OnDelete:CASCADE;"` // 结构化时间段
+	Course          *Course       `gorm:"foreignKey:CourseID"`
+	GroupSession    *GroupSession `gorm:"foreignKey:BookingID"` // 团体课占用的预约才有值
+}
+
+// 预约状态
+const (
+	BookingStatusPending   = "pending"
+	BookingStatusConfirmed = "confirmed"
+	BookingStatusCompleted = "completed"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusNoShow    = "no_show"
+)
+
+// bookingTransitions 列出每个状态允许变更到的下一个状态，已完成、已取消和未到场为终态
+var bookingTransitions = map[string][]string{
+	BookingStatusPending:   {BookingStatusConfirmed, BookingStatusCancelled},
+	BookingStatusConfirmed: {BookingStatusCompleted, BookingStatusCancelled, BookingStatusNoShow},
+}
+
+// IsValidBookingStatus 判断是否为已知的预约状态
+func IsValidBookingStatus(status string) bool {
+	switch status {
+	case BookingStatusPending, BookingStatusConfirmed, BookingStatusCompleted, BookingStatusCancelled, BookingStatusNoShow:
+		return true
+	}
+	return false
+}
+
+// CanTransitionBooking 判断预约状态能否从 from 变更为 to
+func CanTransitionBooking(from, to string) bool {
+	for _, next := range bookingTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinalBookingStatus 判断预约是否已处于终态，终态预约不能再修改
+func IsFinalBookingStatus(status string) bool {
+	return len(bookingTransitions[status]) == 0
+}
+
+// CoachDayLock 对应于 'coach_day_locks' 表，每个教练每天一行；
+// 写入或改动某天的预约前先锁定这一行，使同一教练同一天的预约串行执行
+type CoachDayLock struct {
+	CoachID uint      `gorm:"primaryKey;autoIncrement:false"`
+	Date    time.Time `gorm:"primaryKey;type:date"`
+}
+
+// BookingSeries 对应于 'booking_series' 表，记录按周重复的系列预约，每次课程仍是独立的 Booking
+type BookingSeries struct {
 	ID          uint      `gorm:"primaryKey"`
-	CoachID     uint      `gorm:"not null"`
-	BookingDate time.Time `gorm:"type:date;not null"`
-	TimeSlot    string    `gorm:"type:varchar(50);not null"`
-	ClientInfo  string    `gorm:"type:varchar(255)"`
+	CoachID     uint      `gorm:"not null;index"`
+	RRule       string    `gorm:"column:rrule;type:varchar(255);not null"` // 例如 FREQ=WEEKLY;BYDAY=SA;COUNT=10
+	StartDate   time.Time `gorm:"type:date;not null"`
+	StudentName string    `gorm:"type:varchar(255)"`
+	CreatedBy   uint      `gorm:"not null"`
 	CreatedAt   time.Time
-} 
\ No newline at end of file
+	Bookings    []Booking `gorm:"foreignKey:SeriesID"`
+}
+
+// BookingStatusChange 对应于 'booking_status_changes' 表，记录预约状态变更历史
+type BookingStatusChange struct {
+	ID         uint   `gorm:"primaryKey"`
+	BookingID  uint   `gorm:"not null;index"`
+	FromStatus string `gorm:"type:varchar(20)"`
+	ToStatus   string `gorm:"type:varchar(20);not null"`
+	Reason     string `gorm:"type:varchar(255)"`
+	ChangedBy  uint   `gorm:"not null"` // 操作用户ID
+	CreatedAt  time.Time
+}
+
+// BookingSlot 对应于 'booking_slots' 表，每行是预约中的一个时间段
+type BookingSlot struct {
+	ID        uint   `gorm:"primaryKey"`
+	BookingID uint   `gorm:"not null;index"`
+	StartTime string `gorm:"type:char(5);not null"` // HH:MM
+	EndTime   string `gorm:"type:char(5);not null"` // HH:MM
+}
+
+// Course 对应于 'courses' 表
+type Course struct {
+	ID              uint   `gorm:"primaryKey"`
+	Name            string `gorm:"type:varchar(255);not null"`
+	Description     string `gorm:"type:text"`
+	Price           int    // 单位：元
+	DurationMinutes int    `gorm:"not null;default:0"` // 单次课时长（分钟），0 表示不限
+	Active          bool   `gorm:"not null;default:true"`
+	CreatedAt       time.Time
+}
+
+// GroupSession 对应于 'group_sessions' 表，表示一节多名学员参加的团体课
+// 团体课通过一条预约占用教练的时间，无论报名人数多少，在冲突检查中都只算一个时间块
+type GroupSession struct {
+	ID          uint   `gorm:"primaryKey"`
+	BookingID   uint   `gorm:"not null;unique"`
+	Title       string `gorm:"type:varchar(255);not null"`
+	Capacity    int    `gorm:"not null"`
+	CreatedAt   time.Time
+	Booking     Booking           `gorm:"foreignKey:BookingID"`
+	Enrollments []GroupEnrollment `gorm:"foreignKey:GroupSessionID"`
+}
+
+// 团体课报名状态
+const (
+	EnrollmentStatusEnrolled  = "enrolled"
+	EnrollmentStatusCancelled = "cancelled"
+)
+
+// GroupEnrollment 对应于 'group_enrollments' 表，每行占用团体课的一个名额
+type GroupEnrollment struct {
+	ID             uint   `gorm:"primaryKey"`
+	GroupSessionID uint   `gorm:"not null;index"`
+	StudentName    string `gorm:"type:varchar(255);not null"`
+	Contact        string `gorm:"type:varchar(100)"`
+	Status         string `gorm:"type:varchar(20);not null;default:'enrolled'"` // 见 EnrollmentStatus* 常量
+	Price          int    // 报名时的课程价格快照（元）
+	CreatedBy      uint   `gorm:"not null"`
+	CreatedAt      time.Time
+	CancelledAt    *time.Time
+}
+
+// 候补状态
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusPromoted  = "promoted"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+// WaitlistEntry 对应于 'waitlist_entries' 表，记录因时间段已满而排队候补的学员
+// 有预约被取消时，按创建顺序将可安排的候补自动转为待确认预约
+type WaitlistEntry struct {
+	ID                uint      `gorm:"primaryKey"`
+	CoachID           uint      `gorm:"not null;index:idx_waitlist_coach_date"`
+	Date              time.Time `gorm:"type:date;not null;index:idx_waitlist_coach_date"`
+	TimeSlot          string    `gorm:"type:varchar(255);not null"` // 期望的时间段，格式同 Booking.TimeSlot
+	StudentName       string    `gorm:"type:varchar(255);not null"`
+	StudentID         *uint     // 关联的学员，转为预约时一并带上
+	Contact           string    `gorm:"type:varchar(100)"`
+	CourseID          *uint
+	Status            string `gorm:"type:varchar(20);not null;default:'waiting';index"` // 见 WaitlistStatus* 常量
+	PromotedBookingID *uint
+	PromotedAt        *time.Time
+	NotifiedAt        *time.Time // 工作人员通知学员的时间
+	CreatedBy         uint       `gorm:"not null"`
+	CreatedAt         time.Time
+}
+
+// CoachWorkingHour 对应于 'coach_working_hours' 表，描述教练每周固定的工作时段
+type CoachWorkingHour struct {
+	ID        uint   `gorm:"primaryKey"`
+	CoachID   uint   `gorm:"not null;index"`
+	Weekday   int    `gorm:"not null"`              // 0 表示周日，与 time.Weekday 一致
+	StartTime string `gorm:"type:char(5);not null"` // HH:MM
+	EndTime   string `gorm:"type:char(5);not null"` // HH:MM
+}
+
+// CoachDateOverride 对应于 'coach_date_overrides' 表，用于覆盖某一天的每周工作时段
+// 同一天存在覆盖记录时只使用覆盖记录；Closed 为 true 表示当天不可预约
+type CoachDateOverride struct {
+	ID        uint      `gorm:"primaryKey"`
+	CoachID   uint      `gorm:"not null;index:idx_coach_override_date"`
+	Date      time.Time `gorm:"type:date;not null;index:idx_coach_override_date"`
+	StartTime string    `gorm:"type:char(5)"` // HH:MM，Closed 为 true 时为空
+	EndTime   string    `gorm:"type:char(5)"` // HH:MM，Closed 为 true 时为空
+	Closed    bool      `gorm:"not null;default:false"`
+	Note      string    `gorm:"type:varchar(255)"`
+	CreatedAt time.Time
+}
+
+// Blackout 对应于 'blackouts' 表，记录教练休假或全校停课的时间段
+// CoachID 为空表示全校停课；StartTime/EndTime 为空表示整天
+type Blackout struct {
+	ID        uint      `gorm:"primaryKey"`
+	CoachID   *uint     `gorm:"index"`
+	StartDate time.Time `gorm:"type:date;not null"`
+	EndDate   time.Time `gorm:"type:date;not null"`
+	StartTime string    `gorm:"type:char(5)"` // HH:MM，为空表示整天
+	EndTime   string    `gorm:"type:char(5)"` // HH:MM，为空表示整天
+	Reason    string    `gorm:"type:varchar(255)"`
+	CreatedBy uint      `gorm:"not null"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"classOrder-backend/config"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message 是发给某个用户的一条通知
+type Message struct {
+	UserID   uint
+	Username string
+	Subject  string
+	Body     string
+}
+
+// Notifier 负责把通知送达用户，接入短信、邮件等渠道时实现该接口即可
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// Default 是全局使用的通知渠道，由 InitNotifier 根据配置设置
+var Default Notifier = LogNotifier{}
+
+// InitNotifier 根据配置选择通知渠道
+func InitNotifier() {
+	cfg := config.Cfg.Notify
+	switch cfg.Driver {
+	case "", "log":
+		Default = LogNotifier{}
+	case "file":
+		path := cfg.FilePath
+		if path == "" {
+			path = "notifications.log"
+		}
+		Default = &FileNotifier{Path: path}
+	default:
+		log.Printf("警告: 未知的通知渠道 %q，改为输出到日志", cfg.Driver)
+		Default = LogNotifier{}
+	}
+}
+
+// Send 通过默认渠道发送通知
+func Send(msg Message) error {
+	return Default.Send(msg)
+}
+
+// LogNotifier 把通知输出到服务日志，仅用于本地开发
+type LogNotifier struct{}
+
+func (LogNotifier) Send(msg Message) error {
+	log.Printf("[Notify] to=%s (user %d) subject=%s\n%s", msg.Username, msg.UserID, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier 把通知追加写入文件，便于本地查看
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (n *FileNotifier) Send(msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "[%s] to=%s (user %d) subject=%s\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.Username, msg.UserID, msg.Subject, msg.Body)
+	return err
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"classOrder-backend/internal/api/handlers"
+	"classOrder-backend/internal/models"
 	"classOrder-backend/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -18,41 +19,260 @@ func SetupRouter() *gin.Engine {
 
 	// 公开的登录路由
 	r.POST("/api/login", handlers.LoginHandler)
+	r.POST("/api/login/2fa", handlers.LoginSecondFactorHandler)
+	r.POST("/api/login/2fa/setup", handlers.LoginSetupTOTPHandler)
+	r.POST("/api/login/2fa/enable", handlers.LoginEnableTOTPHandler)
+	r.POST("/api/token/refresh", handlers.RefreshTokenHandler)
+	r.POST("/api/logout", middleware.AllowPendingPasswordChange(), middleware.JWTAuthMiddleware(), handlers.LogoutHandler)
+	r.POST("/api/password-reset/request", handlers.RequestPasswordResetHandler)
+	r.POST("/api/password-reset/confirm", handlers.ConfirmPasswordResetHandler)
+	r.POST("/api/student/register", handlers.RegisterStudentHandler)
 
 	// API路由组
 	api := r.Group("/api")
 	{
-		// 上传文件路由 (需要登录)
-		// 任何登录用户都可以上传，但在教练创建/更新时由管理员使用
-		api.POST("/upload", middleware.JWTAuthMiddleware(), handlers.UploadHandler)
+		// 上传文件路由 (需要预约写权限)
+		// 管理员上传教练头像，教练上传课程报告照片；学员账号可以自助注册，不能上传
+		api.POST("/upload", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionBookingsWrite), handlers.UploadHandler)
 
 		// 教练管理路由
 		coaches := api.Group("/coaches")
 		{
 			coaches.GET("", handlers.ListCoachesHandler)      // 获取教练列表 (公开)
 			coaches.GET("/:id", handlers.GetCoachHandler)     // 获取单个教练信息 (公开)
+			coaches.GET("/:id/availability", handlers.GetCoachAvailabilityHandler)   // 查询教练空闲时间 (公开)
+			coaches.GET("/:id/working-hours", handlers.GetCoachWorkingHoursHandler) // 查询教练每周工作时段 (公开)
+			coaches.GET("/:id/reviews", handlers.ListCoachReviewsHandler)           // 查询教练的学员评价 (公开)
 			
-			// 以下操作需要管理员权限
-			adminCoaches := coaches.Group("", middleware.JWTAuthMiddleware(), middleware.AdminAuthMiddleware())
+			// 以下操作需要教练管理权限
+			adminCoaches := coaches.Group("", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionCoachesManage))
 			{
 				adminCoaches.POST("", handlers.CreateCoachHandler)
 				adminCoaches.PUT("/:id", handlers.UpdateCoachHandler)
 				adminCoaches.DELETE("/:id", handlers.DeleteCoachHandler)
+				adminCoaches.POST("/:id/revoke-sessions", middleware.RequirePermission(models.PermissionSecurityManage), handlers.RevokeCoachSessionsHandler)
+				adminCoaches.PUT("/:id/working-hours", handlers.SetCoachWorkingHoursHandler)
+				adminCoaches.GET("/:id/overrides", handlers.ListCoachOverridesHandler)
+				adminCoaches.PUT("/:id/overrides", handlers.SetCoachOverrideHandler)
+				adminCoaches.DELETE("/:id/overrides/:date", handlers.DeleteCoachOverrideHandler)
+				adminCoaches.PUT("/:id/certifications", handlers.SetCoachCertificationsHandler)
+				adminCoaches.PUT("/:id/specialties", handlers.SetCoachSpecialtiesHandler)
 			}
 		}
 
-		// 预约管理路由
+		// 预约管理路由，没有 bookings:read_all / bookings:write_all 权限的用户只能查看和修改自己的预约
 		bookings := api.Group("/bookings", middleware.JWTAuthMiddleware())
 		{
-			bookings.GET("", handlers.ListBookingsHandler)
-			bookings.POST("", handlers.CreateBookingHandler)
-			bookings.PUT(":id", handlers.UpdateBookingHandler)
-			bookings.DELETE(":id", handlers.DeleteBookingHandler)
+			readBookings := bookings.Group("", middleware.RequirePermission(models.PermissionBookingsRead),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsReadAll))
+			{
+				readBookings.GET("", handlers.ListBookingsHandler)
+				readBookings.GET("/calendar", handlers.BookingCalendarHandler)
+				readBookings.GET(":id/history", middleware.CoachOwnershipMiddleware(&models.Booking{}, "id"), handlers.GetBookingHistoryHandler)
+			}
+
+			// 批量导入已有预约 (需要为任意教练预约的权限)
+			bookings.POST("/import", middleware.RequirePermission(models.PermissionBookingsWriteAll), handlers.ImportBookingsHandler)
+
+			writeBookings := bookings.Group("", middleware.RequirePermission(models.PermissionBookingsWrite),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsWriteAll))
+			{
+				writeBookings.POST("", handlers.CreateBookingHandler)
+
+				ownBooking := writeBookings.Group("", middleware.CoachOwnershipMiddleware(&models.Booking{}, "id"))
+				{
+					ownBooking.PUT(":id", handlers.UpdateBookingHandler)
+					ownBooking.DELETE(":id", handlers.DeleteBookingHandler)
+					ownBooking.POST(":id/confirm", handlers.ConfirmBookingHandler)
+					ownBooking.POST(":id/cancel", handlers.CancelBookingHandler)
+					ownBooking.POST(":id/complete", handlers.CompleteBookingHandler)
+					ownBooking.POST(":id/no-show", handlers.NoShowBookingHandler)
+				}
+			}
+		}
+
+		// 重复预约系列路由，单次预约仍通过 /bookings/:id 修改或取消
+		series := api.Group("/booking-series", middleware.JWTAuthMiddleware())
+		{
+			series.GET("/:id", middleware.RequirePermission(models.PermissionBookingsRead),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsReadAll),
+				middleware.CoachOwnershipMiddleware(&models.BookingSeries{}, "id"), handlers.GetBookingSeriesHandler)
+
+			writeSeries := series.Group("", middleware.RequirePermission(models.PermissionBookingsWrite),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsWriteAll),
+				middleware.CoachOwnershipMiddleware(&models.BookingSeries{}, "id"))
+			{
+				writeSeries.PUT("/:id", handlers.UpdateBookingSeriesHandler)
+				writeSeries.POST("/:id/cancel", handlers.CancelBookingSeriesHandler)
+			}
+		}
+
+		// 候补路由，教练只能管理自己的候补
+		waitlist := api.Group("/waitlist", middleware.JWTAuthMiddleware())
+		{
+			waitlist.GET("", middleware.RequirePermission(models.PermissionBookingsRead),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsReadAll), handlers.ListWaitlistHandler)
+
+			writeWaitlist := waitlist.Group("", middleware.RequirePermission(models.PermissionBookingsWrite),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsWriteAll))
+			{
+				writeWaitlist.POST("", handlers.CreateWaitlistHandler)
+				writeWaitlist.DELETE("/:id", middleware.CoachOwnershipMiddleware(&models.WaitlistEntry{}, "id"), handlers.CancelWaitlistHandler)
+				writeWaitlist.POST("/:id/notified", middleware.CoachOwnershipMiddleware(&models.WaitlistEntry{}, "id"), handlers.MarkWaitlistNotifiedHandler)
+			}
+		}
+
+		// 团体课路由，教练只能查看和管理自己的团体课
+		groupSessions := api.Group("/group-sessions", middleware.JWTAuthMiddleware())
+		{
+			readGroupSessions := groupSessions.Group("", middleware.RequirePermission(models.PermissionBookingsRead),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsReadAll))
+			{
+				readGroupSessions.GET("", handlers.ListGroupSessionsHandler)
+				readGroupSessions.GET("/:id", handlers.GetGroupSessionHandler)
+			}
+
+			writeGroupSessions := groupSessions.Group("", middleware.RequirePermission(models.PermissionBookingsWrite),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsWriteAll))
+			{
+				writeGroupSessions.POST("", handlers.CreateGroupSessionHandler)
+				writeGroupSessions.PUT("/:id", handlers.UpdateGroupSessionHandler)
+				writeGroupSessions.POST("/:id/enrollments", handlers.EnrollGroupSessionHandler)
+				writeGroupSessions.DELETE("/:id/enrollments/:enrollmentId", handlers.CancelEnrollmentHandler)
+			}
+		}
+
+		// 课程路由
+		courses := api.Group("/courses")
+		{
+			courses.GET("", handlers.ListCoursesHandler)  // 获取在售课程列表 (公开)
+			courses.GET("/:id", handlers.GetCourseHandler) // 获取单个课程信息 (公开)
+
+			// 以下操作需要课程管理权限
+			adminCourses := courses.Group("", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionCoursesManage))
+			{
+				adminCourses.GET("/all", handlers.ListAllCoursesHandler)
+				adminCourses.POST("", handlers.CreateCourseHandler)
+				adminCourses.PUT("/:id", handlers.UpdateCourseHandler)
+				adminCourses.DELETE("/:id", handlers.DeleteCourseHandler)
+			}
+		}
+
+		// 日历订阅路由，订阅链接凭令牌访问，无需登录；全校订阅链接由安全管理权限维护
+		calendar := api.Group("/calendar")
+		{
+			calendar.GET("/feeds/:token", handlers.CalendarFeedHandler)
+			calendar.POST("/school-token", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionSecurityManage), handlers.RegenerateSchoolCalendarTokenHandler)
+			calendar.DELETE("/school-token", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionSecurityManage), handlers.RevokeSchoolCalendarTokenHandler)
+		}
+
+		// 学员评价审核路由 (需要评价审核权限)
+		reviews := api.Group("/reviews", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionReviewsModerate))
+		{
+			reviews.GET("", handlers.ListReviewsHandler)
+			reviews.POST("/:id/approve", handlers.ApproveReviewHandler)
+			reviews.POST("/:id/hide", handlers.HideReviewHandler)
+		}
+
+		// 即将到期的教练证书 (需要教练管理权限)
+		api.GET("/certifications/expiring", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionCoachesManage), handlers.ListExpiringCertificationsHandler)
+
+		// 登录锁定管理路由 (需要安全管理权限)
+		loginLockouts := api.Group("/login-lockouts", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionSecurityManage))
+		{
+			loginLockouts.GET("", handlers.ListLoginThrottlesHandler)
+			loginLockouts.POST("/unlock", handlers.UnlockLoginHandler)
+		}
+
+		// 休假/停课管理路由 (需要停课管理权限)
+		blackouts := api.Group("/blackouts", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionBlackoutsManage))
+		{
+			blackouts.GET("", handlers.ListBlackoutsHandler)
+			blackouts.POST("", handlers.CreateBlackoutHandler)
+			blackouts.GET("/:id/bookings", handlers.GetBlackoutBookingsHandler)
+			blackouts.DELETE("/:id", handlers.DeleteBlackoutHandler)
+		}
+
+		// 账号管理路由 (需要账号管理权限)
+		users := api.Group("/users", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionUsersManage))
+		{
+			users.GET("", handlers.ListUsersHandler)
+			users.POST("", handlers.CreateUserHandler)
+			users.GET("/:id", handlers.GetUserHandler)
+			users.PUT("/:id/role", handlers.UpdateUserRoleHandler)
+			users.PUT("/:id/password", handlers.SetUserPasswordHandler)
+			users.POST("/:id/disable", handlers.DisableUserHandler)
+			users.POST("/:id/enable", handlers.EnableUserHandler)
+			users.POST("/:id/require-password-change", handlers.RequirePasswordChangeHandler)
+		}
+
+		// 角色与权限管理路由 (需要角色管理权限)
+		roles := api.Group("/roles", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionRolesManage))
+		{
+			roles.GET("", handlers.ListRolesHandler)
+			roles.PUT("/:name", handlers.SaveRoleHandler)
+			roles.DELETE("/:name", handlers.DeleteRoleHandler)
+		}
+
+		// 修改自己的密码，被要求修改密码的账号也可以访问
+		api.POST("/account/password", middleware.AllowPendingPasswordChange(), middleware.JWTAuthMiddleware(), handlers.ChangeOwnPasswordHandler)
+
+		// 当前账号的权限（仅需登录）
+		api.GET("/account/permissions", middleware.JWTAuthMiddleware(), handlers.GetOwnPermissionsHandler)
+
+		// 当前账号的两步验证设置（仅需登录）
+		twoFactor := api.Group("/account/2fa", middleware.JWTAuthMiddleware())
+		{
+			twoFactor.GET("", handlers.GetTOTPStatusHandler)
+			twoFactor.POST("/setup", handlers.SetupTOTPHandler)
+			twoFactor.POST("/enable", handlers.EnableTOTPHandler)
+			twoFactor.POST("/disable", handlers.DisableTOTPHandler)
+			twoFactor.POST("/recovery-codes", handlers.RegenerateRecoveryCodesHandler)
+		}
+
+		// 学员资料路由，工作人员预约时搜索和选择已有学员；教练只能查看和修改在自己名下有过预约的学员
+		students := api.Group("/students", middleware.JWTAuthMiddleware())
+		{
+			readStudents := students.Group("", middleware.RequirePermission(models.PermissionBookingsRead),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsReadAll))
+			{
+				readStudents.GET("", handlers.SearchStudentsHandler)
+				readStudents.GET("/:id", handlers.GetStudentHandler)
+				readStudents.GET("/:id/progress", handlers.GetStudentProgressHandler)
+			}
+			students.POST("", middleware.RequirePermission(models.PermissionBookingsWrite), handlers.CreateStudentHandler)
+			students.PUT("/:id", middleware.RequirePermission(models.PermissionBookingsWrite),
+				middleware.CoachScopeMiddleware(models.PermissionBookingsWriteAll), handlers.UpdateStudentHandler)
+		}
+
+		// 学员自助预约，学员只能查看和取消自己的预约
+		student := api.Group("/student", middleware.JWTAuthMiddleware(), middleware.RequirePermission(models.PermissionBookingsSelf))
+		{
+			student.GET("/profile", handlers.GetOwnStudentProfileHandler)
+			student.GET("/progress", handlers.GetOwnProgressHandler)
+			student.GET("/bookings", handlers.ListOwnBookingsHandler)
+			student.POST("/bookings", handlers.CreateOwnBookingHandler)
+			student.POST("/bookings/:id/cancel", handlers.CancelOwnBookingHandler)
+			student.POST("/bookings/:id/review", handlers.CreateOwnReviewHandler)
+			student.GET("/reviews", handlers.ListOwnReviewsHandler)
 		}
 
 		// 教练自助管理个人信息（仅需登录）
 		api.GET("/coach/profile", middleware.JWTAuthMiddleware(), handlers.GetOwnCoachProfileHandler)
 		api.PUT("/coach/profile", middleware.JWTAuthMiddleware(), handlers.UpdateOwnCoachProfileHandler)
+		api.POST("/coach/profile/calendar-token", middleware.JWTAuthMiddleware(), handlers.RegenerateOwnCalendarTokenHandler)
+		api.DELETE("/coach/profile/calendar-token", middleware.JWTAuthMiddleware(), handlers.RevokeOwnCalendarTokenHandler)
+		api.PUT("/coach/specialties", middleware.JWTAuthMiddleware(), handlers.SetOwnSpecialtiesHandler)
+		api.GET("/coach/working-hours", middleware.JWTAuthMiddleware(), handlers.GetOwnWorkingHoursHandler)
+		api.PUT("/coach/working-hours", middleware.JWTAuthMiddleware(), handlers.SetOwnWorkingHoursHandler)
+		api.GET("/coach/overrides", middleware.JWTAuthMiddleware(), handlers.ListOwnOverridesHandler)
+		api.PUT("/coach/overrides", middleware.JWTAuthMiddleware(), handlers.SetOwnOverrideHandler)
+		api.DELETE("/coach/overrides/:date", middleware.JWTAuthMiddleware(), handlers.DeleteOwnOverrideHandler)
+		api.GET("/coach/blackouts", middleware.JWTAuthMiddleware(), handlers.ListOwnBlackoutsHandler)
+		api.POST("/coach/blackouts", middleware.JWTAuthMiddleware(), handlers.CreateOwnBlackoutHandler)
+		api.DELETE("/coach/blackouts/:id", middleware.JWTAuthMiddleware(), handlers.DeleteOwnBlackoutHandler)
+		api.GET("/coach/bookings/:id/report", middleware.JWTAuthMiddleware(), handlers.GetOwnLessonReportHandler)
+		api.PUT("/coach/bookings/:id/report", middleware.JWTAuthMiddleware(), handlers.SaveOwnLessonReportHandler)
 	}
 
 	return r
@@ -0,0 +1,110 @@
+package schedule
+
+import "sort"
+
+// interval 是以当天分钟数表示的半开区间 [start, end)
+type interval struct {
+	start, end int
+}
+
+func toIntervals(slots []Slot) []interval {
+	result := make([]interval, 0, len(slots))
+	for _, s := range slots {
+		start, err := ParseClock(s.Start)
+		if err != nil {
+			continue
+		}
+		end, err := ParseClock(s.End)
+		if err != nil || end <= start {
+			continue
+		}
+		result = append(result, interval{start, end})
+	}
+	return result
+}
+
+func fromIntervals(intervals []interval) []Slot {
+	result := make([]Slot, 0, len(intervals))
+	for _, iv := range intervals {
+		result = append(result, Slot{Start: FormatClock(iv.start), End: FormatClock(iv.end)})
+	}
+	return result
+}
+
+func mergeIntervals(intervals []interval) []interval {
+	if len(intervals) == 0 {
+		return nil
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+	merged := []interval{intervals[0]}
+	for _, iv := range intervals[1:] {
+		last := &merged[len(merged)-1]
+		if iv.start <= last.end {
+			if iv.end > last.end {
+				last.end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// Merge 合并重叠或首尾相接的时间段，并按开始时间排序
+func Merge(slots []Slot) []Slot {
+	return fromIntervals(mergeIntervals(toIntervals(slots)))
+}
+
+// Subtract 从 windows 中扣除 busy 覆盖的部分，返回剩余的空闲时间段
+func Subtract(windows, busy []Slot) []Slot {
+	free := mergeIntervals(toIntervals(windows))
+	for _, b := range mergeIntervals(toIntervals(busy)) {
+		var next []interval
+		for _, f := range free {
+			if b.end <= f.start || b.start >= f.end {
+				next = append(next, f)
+				continue
+			}
+			if b.start > f.start {
+				next = append(next, interval{f.start, b.start})
+			}
+			if b.end < f.end {
+				next = append(next, interval{b.end, f.end})
+			}
+		}
+		free = next
+	}
+	return fromIntervals(free)
+}
+
+// Split 将时间段按固定分钟数切分为若干单元，不足一个单元的尾部会被丢弃
+func Split(windows []Slot, minutes int) []Slot {
+	if minutes <= 0 {
+		return nil
+	}
+	var units []interval
+	for _, w := range mergeIntervals(toIntervals(windows)) {
+		for start := w.start; start+minutes <= w.end; start += minutes {
+			units = append(units, interval{start, start + minutes})
+		}
+	}
+	return fromIntervals(units)
+}
+
+// Covers 判断 slots 中的每个时间段是否都完整落在 windows 之内
+func Covers(windows, slots []Slot) bool {
+	merged := mergeIntervals(toIntervals(windows))
+	for _, s := range toIntervals(slots) {
+		covered := false
+		for _, w := range merged {
+			if s.start >= w.start && s.end <= w.end {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []Slot
+		want []Slot
+	}{
+		{"empty", nil, []Slot{}},
+		{"unsorted disjoint", []Slot{{"14:00", "15:00"}, {"09:00", "10:00"}}, []Slot{{"09:00", "10:00"}, {"14:00", "15:00"}}},
+		{"overlapping", []Slot{{"09:00", "10:30"}, {"10:00", "11:00"}}, []Slot{{"09:00", "11:00"}}},
+		{"adjacent", []Slot{{"09:00", "10:00"}, {"10:00", "11:00"}}, []Slot{{"09:00", "11:00"}}},
+		{"contained", []Slot{{"09:00", "12:00"}, {"10:00", "11:00"}}, []Slot{{"09:00", "12:00"}}},
+		{"invalid dropped", []Slot{{"09:00", "10:00"}, {"bad", "11:00"}, {"12:00", "11:00"}}, []Slot{{"09:00", "10:00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Merge(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	day := []Slot{{"09:00", "17:00"}}
+	tests := []struct {
+		name    string
+		windows []Slot
+		busy    []Slot
+		want    []Slot
+	}{
+		{"nothing busy", day, nil, []Slot{{"09:00", "17:00"}}},
+		{"middle", day, []Slot{{"12:00", "13:00"}}, []Slot{{"09:00", "12:00"}, {"13:00", "17:00"}}},
+		{"start and end", day, []Slot{{"08:00", "10:00"}, {"16:00", "18:00"}}, []Slot{{"10:00", "16:00"}}},
+		{"fully busy", day, []Slot{{"09:00", "17:00"}}, []Slot{}},
+		{"outside window", day, []Slot{{"07:00", "08:00"}, {"18:00", "19:00"}}, []Slot{{"09:00", "17:00"}}},
+		{"overlapping busy", day, []Slot{{"10:00", "11:30"}, {"11:00", "12:00"}}, []Slot{{"09:00", "10:00"}, {"12:00", "17:00"}}},
+		{"split windows", []Slot{{"09:00", "12:00"}, {"13:00", "17:00"}}, []Slot{{"11:00", "14:00"}}, []Slot{{"09:00", "11:00"}, {"14:00", "17:00"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Subtract(tt.windows, tt.busy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Subtract(%v, %v) = %v, want %v", tt.windows, tt.busy, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []Slot
+		minutes int
+		want    []Slot
+	}{
+		{"hourly", []Slot{{"09:00", "12:00"}}, 60, []Slot{{"09:00", "10:00"}, {"10:00", "11:00"}, {"11:00", "12:00"}}},
+		{"tail dropped", []Slot{{"09:00", "10:45"}}, 30, []Slot{{"09:00", "09:30"}, {"09:30", "10:00"}, {"10:00", "10:30"}}},
+		{"too short", []Slot{{"09:00", "09:45"}}, 60, nil},
+		{"merged first", []Slot{{"09:00", "09:30"}, {"09:30", "10:00"}}, 60, []Slot{{"09:00", "10:00"}}},
+		{"zero minutes", []Slot{{"09:00", "12:00"}}, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.windows, tt.minutes)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%v, %d) = %v, want %v", tt.windows, tt.minutes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCovers(t *testing.T) {
+	windows := []Slot{{"09:00", "12:00"}, {"12:00", "13:00"}, {"14:00", "17:00"}}
+	tests := []struct {
+		slots []Slot
+		want  bool
+	}{
+		{[]Slot{{"09:00", "10:00"}}, true},
+		{[]Slot{{"11:00", "13:00"}}, true}, // 首尾相接的工作时段视为连续
+		{[]Slot{{"09:00", "10:00"}, {"15:00", "16:00"}}, true},
+		{[]Slot{{"12:30", "14:30"}}, false},
+		{[]Slot{{"08:30", "09:30"}}, false},
+		{[]Slot{{"16:00", "17:30"}}, false},
+		{nil, true},
+	}
+	for _, tt := range tests {
+		if got := Covers(windows, tt.slots); got != tt.want {
+			t.Errorf("Covers(%v) = %v, want %v", tt.slots, got, tt.want)
+		}
+	}
+}
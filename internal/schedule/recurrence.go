@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence 是 RRULE 的子集，目前只支持按周重复（FREQ=WEEKLY），
+// 必须通过 COUNT 或 UNTIL 指定结束条件
+type Recurrence struct {
+	Interval int            // 每隔几周重复一次，默认为 1
+	Count    int            // 总次数，0 表示未指定
+	Until    time.Time      // 最后日期（含），零值表示未指定
+	ByDay    []time.Weekday // 每周的哪几天，为空时使用开始日期所在的星期
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRRule 解析形如 "FREQ=WEEKLY;INTERVAL=1;BYDAY=SA;COUNT=10" 的重复规则
+func ParseRRule(rule string) (Recurrence, error) {
+	r := Recurrence{Interval: 1}
+	rule = strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:")
+	freq := ""
+	for _, part := range strings.Split(rule, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, fmt.Errorf("重复规则格式错误: %q", part)
+		}
+		key, value := strings.ToUpper(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+		switch key {
+		case "FREQ":
+			freq = strings.ToUpper(value)
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("INTERVAL 必须为正整数: %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("COUNT 必须为正整数: %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				wd, ok := rruleWeekdays[strings.ToUpper(strings.TrimSpace(day))]
+				if !ok {
+					return r, fmt.Errorf("BYDAY 取值错误: %q", day)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return r, fmt.Errorf("不支持的重复规则字段: %s", key)
+		}
+	}
+	if freq != "WEEKLY" {
+		return r, fmt.Errorf("目前只支持 FREQ=WEEKLY")
+	}
+	if r.Count == 0 && r.Until.IsZero() {
+		return r, fmt.Errorf("重复规则必须指定 COUNT 或 UNTIL")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return r, fmt.Errorf("COUNT 与 UNTIL 不能同时指定")
+	}
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "2006-01-02", "20060102T150405Z"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL 日期格式错误: %q", value)
+}
+
+// Occurrences 从 start（含）开始展开重复规则，返回每次的日期；
+// 展开结果超过 max 次时返回错误，避免生成过多预约
+func (r Recurrence) Occurrences(start time.Time, max int) ([]time.Time, error) {
+	byDay := make(map[time.Weekday]bool)
+	for _, wd := range r.ByDay {
+		byDay[wd] = true
+	}
+	if len(byDay) == 0 {
+		byDay[start.Weekday()] = true
+	}
+	// 以周一为一周的开始计算周序号，与 RRULE 默认的 WKST=MO 一致
+	offset := (int(start.Weekday()) + 6) % 7
+	weekStart := start.AddDate(0, 0, -offset)
+
+	var dates []time.Time
+	for d := start; ; d = d.AddDate(0, 0, 1) {
+		if !r.Until.IsZero() && d.After(r.Until) {
+			break
+		}
+		if r.Count > 0 && len(dates) >= r.Count {
+			break
+		}
+		week := int(d.Sub(weekStart).Hours()/24) / 7
+		if week%r.Interval == 0 && byDay[d.Weekday()] {
+			if len(dates) >= max {
+				return nil, fmt.Errorf("重复次数超过上限 %d 次", max)
+			}
+			dates = append(dates, d)
+		}
+	}
+	return dates, nil
+}
@@ -0,0 +1,126 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, t.Format("2006-01-02"))
+	}
+	return out
+}
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    Recurrence
+		wantErr bool
+	}{
+		{"FREQ=WEEKLY;COUNT=10", Recurrence{Interval: 1, Count: 10}, false},
+		{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU;COUNT=4", Recurrence{Interval: 2, Count: 4, ByDay: []time.Weekday{time.Saturday, time.Sunday}}, false},
+		{"freq=weekly;byday=mo;until=20250131", Recurrence{Interval: 1, Until: date("2025-01-31"), ByDay: []time.Weekday{time.Monday}}, false},
+		{"FREQ=WEEKLY;UNTIL=2025-01-31", Recurrence{Interval: 1, Until: date("2025-01-31")}, false},
+		{"FREQ=WEEKLY;UNTIL=20250131T235959Z", Recurrence{Interval: 1, Until: date("2025-01-31")}, false},
+		{"FREQ=DAILY;COUNT=3", Recurrence{}, true},
+		{"FREQ=WEEKLY", Recurrence{}, true},
+		{"FREQ=WEEKLY;COUNT=3;UNTIL=20250131", Recurrence{}, true},
+		{"FREQ=WEEKLY;COUNT=0", Recurrence{}, true},
+		{"FREQ=WEEKLY;INTERVAL=0;COUNT=3", Recurrence{}, true},
+		{"FREQ=WEEKLY;BYDAY=XX;COUNT=3", Recurrence{}, true},
+		{"FREQ=WEEKLY;UNTIL=31/01/2025", Recurrence{}, true},
+		{"FREQ=WEEKLY;BYMONTH=1;COUNT=3", Recurrence{}, true},
+		{"FREQ=WEEKLY;COUNT", Recurrence{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRRule(tt.rule)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRRule(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRRule(%q) = %+v, want %+v", tt.rule, got, tt.want)
+		}
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		want  []string
+	}{
+		{"count on start weekday", "FREQ=WEEKLY;COUNT=3", "2025-01-04",
+			[]string{"2025-01-04", "2025-01-11", "2025-01-18"}},
+		{"byday within week", "FREQ=WEEKLY;BYDAY=SA,SU;COUNT=4", "2025-01-04",
+			[]string{"2025-01-04", "2025-01-05", "2025-01-11", "2025-01-12"}},
+		// 开始日期之前的 BYDAY 不计入
+		{"byday before start skipped", "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", "2025-01-08",
+			[]string{"2025-01-10", "2025-01-13", "2025-01-17"}},
+		{"interval", "FREQ=WEEKLY;INTERVAL=2;COUNT=3", "2025-01-06",
+			[]string{"2025-01-06", "2025-01-20", "2025-02-03"}},
+		// 每隔一周按周一开始的周计算，周日与同一周的周一属于同一周
+		{"interval with sunday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;COUNT=4", "2025-01-06",
+			[]string{"2025-01-06", "2025-01-12", "2025-01-20", "2025-01-26"}},
+		{"until inclusive", "FREQ=WEEKLY;UNTIL=20250118", "2025-01-04",
+			[]string{"2025-01-04", "2025-01-11", "2025-01-18"}},
+		{"until before start", "FREQ=WEEKLY;UNTIL=20250101", "2025-01-04", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+			}
+			got, err := r.Occurrences(date(tt.start), 52)
+			if err != nil {
+				t.Fatalf("Occurrences: %v", err)
+			}
+			if !reflect.DeepEqual(dates(got), tt.want) {
+				t.Errorf("Occurrences(%s) = %v, want %v", tt.start, dates(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesLimit(t *testing.T) {
+	start := date("2025-01-04")
+	tests := []struct {
+		rule    string
+		wantLen int
+		wantErr bool
+	}{
+		{"FREQ=WEEKLY;COUNT=52", 52, false},
+		{"FREQ=WEEKLY;COUNT=53", 0, true},
+		{"FREQ=WEEKLY;BYDAY=SA,SU;COUNT=52", 52, false},
+		{"FREQ=WEEKLY;BYDAY=SA,SU;UNTIL=20251231", 0, true},
+		{"FREQ=WEEKLY;UNTIL=20251227", 52, false},
+		{"FREQ=WEEKLY;UNTIL=20260103", 0, true},
+	}
+	for _, tt := range tests {
+		r, err := ParseRRule(tt.rule)
+		if err != nil {
+			t.Fatalf("ParseRRule(%q): %v", tt.rule, err)
+		}
+		got, err := r.Occurrences(start, 52)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Occurrences(%q) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+			continue
+		}
+		if len(got) != tt.wantLen {
+			t.Errorf("Occurrences(%q) returned %d dates, want %d", tt.rule, len(got), tt.wantLen)
+		}
+	}
+}
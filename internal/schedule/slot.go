@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Slot 表示一天内的一个时间段，起止时间均为 HH:MM 格式
+type Slot struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// ParseClock 将 HH:MM 格式的时间解析为当天的分钟数
+func ParseClock(s string) (int, error) {
+	if len(s) != 5 || s[2] != ':' {
+		return 0, fmt.Errorf("时间格式错误: %q，应为 HH:MM", s)
+	}
+	for _, i := range []int{0, 1, 3, 4} {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, fmt.Errorf("时间格式错误: %q，应为 HH:MM", s)
+		}
+	}
+	hour := int(s[0]-'0')*10 + int(s[1]-'0')
+	minute := int(s[3]-'0')*10 + int(s[4]-'0')
+	if hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("时间超出范围: %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// FormatClock 将当天的分钟数格式化为 HH:MM
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Validate 校验时间段格式，并要求结束时间晚于开始时间
+func (s Slot) Validate() error {
+	start, err := ParseClock(s.Start)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(s.End)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("时间段 %s 的结束时间必须晚于开始时间", s)
+	}
+	return nil
+}
+
+// Overlaps 判断两个时间段是否重叠（首尾相接不算重叠）
+func (s Slot) Overlaps(o Slot) bool {
+	return !(s.End <= o.Start || s.Start >= o.End)
+}
+
+// String 返回 "09:00-10:00" 形式的字符串
+func (s Slot) String() string {
+	return s.Start + "-" + s.End
+}
+
+// ParseSlots 严格解析逗号分隔的时间段字符串，任何一段格式错误都会返回错误
+func ParseSlots(raw string) ([]Slot, error) {
+	var slots []Slot
+	for _, piece := range strings.Split(raw, ",") {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+		slot, err := parseSlot(piece)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return Normalize(slots)
+}
+
+// ParseSlotsLenient 宽松解析时间段字符串，返回合法的时间段以及无法解析的片段，
+// 仅用于迁移历史数据
+func ParseSlotsLenient(raw string) ([]Slot, []string) {
+	var slots []Slot
+	var invalid []string
+	for _, piece := range strings.Split(raw, ",") {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+		slot, err := parseSlot(piece)
+		if err != nil {
+			invalid = append(invalid, piece)
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	return slots, invalid
+}
+
+func parseSlot(piece string) (Slot, error) {
+	parts := strings.Split(piece, "-")
+	if len(parts) != 2 {
+		return Slot{}, fmt.Errorf("时间段格式错误: %q，应为 HH:MM-HH:MM", piece)
+	}
+	slot := Slot{Start: strings.TrimSpace(parts[0]), End: strings.TrimSpace(parts[1])}
+	if err := slot.Validate(); err != nil {
+		return Slot{}, err
+	}
+	return slot, nil
+}
+
+// Normalize 校验每个时间段，按开始时间排序，并拒绝相互重叠的时间段
+func Normalize(slots []Slot) ([]Slot, error) {
+	if len(slots) == 0 {
+		return nil, fmt.Errorf("至少需要一个时间段")
+	}
+	sorted := make([]Slot, len(slots))
+	copy(sorted, slots)
+	for _, s := range sorted {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Overlaps(sorted[i-1]) {
+			return nil, fmt.Errorf("时间段 %s 与 %s 重叠", sorted[i-1], sorted[i])
+		}
+	}
+	return sorted, nil
+}
+
+// Format 将时间段拼接为旧版前端使用的逗号分隔字符串
+func Format(slots []Slot) string {
+	pieces := make([]string, 0, len(slots))
+	for _, s := range slots {
+		pieces = append(pieces, s.String())
+	}
+	return strings.Join(pieces, ", ")
+}
+
+// AnyOverlap 判断两组时间段之间是否存在重叠，返回第一对冲突的时间段
+func AnyOverlap(a, b []Slot) (Slot, Slot, bool) {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Overlaps(y) {
+				return x, y, true
+			}
+		}
+	}
+	return Slot{}, Slot{}, false
+}
+
+// Duration 返回一组时间段的总时长（分钟）
+func Duration(slots []Slot) int {
+	total := 0
+	for _, iv := range toIntervals(slots) {
+		total += iv.end - iv.start
+	}
+	return total
+}
+
+// SlotFrom 根据开始时间和时长构造时间段
+func SlotFrom(start string, minutes int) (Slot, error) {
+	begin, err := ParseClock(start)
+	if err != nil {
+		return Slot{}, err
+	}
+	if minutes <= 0 || begin+minutes > 23*60+59 {
+		return Slot{}, fmt.Errorf("从 %s 开始的 %d 分钟超出当天范围", start, minutes)
+	}
+	return Slot{Start: start, End: FormatClock(begin + minutes)}, nil
+}
@@ -0,0 +1,156 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"00:00", 0, false},
+		{"09:30", 570, false},
+		{"23:59", 1439, false},
+		{"24:00", 0, true},
+		{"12:60", 0, true},
+		{"9:30", 0, true},
+		{"09-30", 0, true},
+		{"ab:cd", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseClock(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseClock(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseClock(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseSlots(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []Slot
+		wantErr bool
+	}{
+		{"single", "09:00-10:00", []Slot{{"09:00", "10:00"}}, false},
+		{"sorted with spaces", " 14:00 - 15:00 , 09:00-10:00 ", []Slot{{"09:00", "10:00"}, {"14:00", "15:00"}}, false},
+		{"adjacent", "09:00-10:00,10:00-11:00", []Slot{{"09:00", "10:00"}, {"10:00", "11:00"}}, false},
+		{"empty pieces skipped", "09:00-10:00,,", []Slot{{"09:00", "10:00"}}, false},
+		{"empty", "", nil, true},
+		{"overlapping", "09:00-10:30,10:00-11:00", nil, true},
+		{"end before start", "10:00-09:00", nil, true},
+		{"zero length", "10:00-10:00", nil, true},
+		{"missing end", "09:00", nil, true},
+		{"bad clock", "9:00-10:00", nil, true},
+		{"one bad piece", "09:00-10:00,foo", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSlots(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSlots(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSlots(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSlotsLenient(t *testing.T) {
+	tests := []struct {
+		in          string
+		wantSlots   []Slot
+		wantInvalid []string
+	}{
+		{"09:00-10:00, 10:00-11:00", []Slot{{"09:00", "10:00"}, {"10:00", "11:00"}}, nil},
+		{"09:00-10:00, 上午, 14:00-13:00", []Slot{{"09:00", "10:00"}}, []string{"上午", "14:00-13:00"}},
+		{"全天", nil, []string{"全天"}},
+		{"", nil, nil},
+		// 宽松解析不检查重叠，保留原始顺序
+		{"10:00-11:00,09:30-10:30", []Slot{{"10:00", "11:00"}, {"09:30", "10:30"}}, nil},
+	}
+	for _, tt := range tests {
+		slots, invalid := ParseSlotsLenient(tt.in)
+		if !reflect.DeepEqual(slots, tt.wantSlots) || !reflect.DeepEqual(invalid, tt.wantInvalid) {
+			t.Errorf("ParseSlotsLenient(%q) = %v, %v; want %v, %v", tt.in, slots, invalid, tt.wantSlots, tt.wantInvalid)
+		}
+	}
+}
+
+func TestSlotOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b Slot
+		want bool
+	}{
+		{Slot{"09:00", "10:00"}, Slot{"09:30", "10:30"}, true},
+		{Slot{"09:00", "12:00"}, Slot{"10:00", "11:00"}, true},
+		{Slot{"09:00", "10:00"}, Slot{"09:00", "10:00"}, true},
+		{Slot{"09:00", "10:00"}, Slot{"10:00", "11:00"}, false},
+		{Slot{"10:00", "11:00"}, Slot{"09:00", "10:00"}, false},
+		{Slot{"09:00", "10:00"}, Slot{"13:00", "14:00"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Overlaps(tt.b); got != tt.want {
+			t.Errorf("%s.Overlaps(%s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := tt.b.Overlaps(tt.a); got != tt.want {
+			t.Errorf("%s.Overlaps(%s) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	got := Format([]Slot{{"09:00", "10:00"}, {"14:00", "15:30"}})
+	if want := "09:00-10:00, 14:00-15:30"; got != want {
+		t.Errorf("Format = %q, want %q", got, want)
+	}
+	// Format 的结果可以被 ParseSlots 重新解析
+	slots, err := ParseSlots(got)
+	if err != nil || len(slots) != 2 {
+		t.Errorf("ParseSlots(Format(...)) = %v, %v", slots, err)
+	}
+}
+
+func TestSlotFrom(t *testing.T) {
+	tests := []struct {
+		start   string
+		minutes int
+		want    Slot
+		wantErr bool
+	}{
+		{"09:00", 90, Slot{"09:00", "10:30"}, false},
+		{"22:00", 119, Slot{"22:00", "23:59"}, false},
+		{"23:00", 60, Slot{}, true},
+		{"09:00", 0, Slot{}, true},
+		{"9:00", 60, Slot{}, true},
+	}
+	for _, tt := range tests {
+		got, err := SlotFrom(tt.start, tt.minutes)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("SlotFrom(%q, %d) = %v, %v; want %v, wantErr %v", tt.start, tt.minutes, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAnyOverlapAndDuration(t *testing.T) {
+	a := []Slot{{"09:00", "10:00"}, {"14:00", "15:00"}}
+	if _, _, ok := AnyOverlap(a, []Slot{{"10:00", "14:00"}}); ok {
+		t.Errorf("AnyOverlap reported a conflict for adjacent slots")
+	}
+	x, y, ok := AnyOverlap(a, []Slot{{"11:00", "12:00"}, {"14:30", "16:00"}})
+	if !ok || x != a[1] || y != (Slot{"14:30", "16:00"}) {
+		t.Errorf("AnyOverlap = %v, %v, %v", x, y, ok)
+	}
+	if got := Duration([]Slot{{"09:00", "10:00"}, {"10:30", "12:00"}, {"14:00", "14:45"}}); got != 195 {
+		t.Errorf("Duration = %d, want 195", got)
+	}
+}
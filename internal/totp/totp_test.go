@@ -0,0 +1,116 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret 是 RFC 6238 附录 B 中 SHA1 测试用的密钥 "12345678901234567890" 的 base32 编码
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeAtRFC6238 使用 RFC 6238 附录 B 的测试向量，验证码取 8 位结果的后 6 位
+func TestCodeAtRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := CodeAt(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("CodeAt(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("CodeAt(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAtSecretFormatting(t *testing.T) {
+	want, _ := CodeAt(rfcSecret, 1)
+	// 用户手动输入的密钥可能是小写或带空格
+	spaced := strings.ToLower(rfcSecret[:8] + " " + rfcSecret[8:16] + " " + rfcSecret[16:])
+	got, err := CodeAt(spaced, 1)
+	if err != nil || got != want {
+		t.Errorf("CodeAt(%q) = %s, %v; want %s", spaced, got, err, want)
+	}
+	if _, err := CodeAt("not base32!", 1); err == nil {
+		t.Errorf("CodeAt accepted an invalid secret")
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	tests := []struct {
+		name     string
+		step     int64
+		wantStep int64
+		ok       bool
+	}{
+		{"current step", current, current, true},
+		{"previous step", current - 1, current - 1, true},
+		{"next step", current + 1, current + 1, true},
+		{"two steps behind", current - 2, 0, false},
+		{"two steps ahead", current + 2, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := CodeAt(rfcSecret, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			step, ok := Validate(rfcSecret, code, now, 0)
+			if ok != tt.ok || step != tt.wantStep {
+				t.Errorf("Validate = %d, %v; want %d, %v", step, ok, tt.wantStep, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := CodeAt(rfcSecret, Step(now))
+	if _, ok := Validate(rfcSecret, " "+code+" ", now, 0); !ok {
+		t.Errorf("Validate rejected a code with surrounding spaces")
+	}
+	for _, bad := range []string{"", "12345", "1234567", code[:5] + "x"} {
+		if _, ok := Validate(rfcSecret, bad, now, 0); ok {
+			t.Errorf("Validate accepted %q", bad)
+		}
+	}
+}
+
+// TestValidateReplay 模拟登录时记录的 TOTPLastStep：已使用过的时间步及更早的时间步都不能再次通过
+func TestValidateReplay(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code, _ := CodeAt(rfcSecret, Step(now))
+
+	lastStep, ok := Validate(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("first use of the code was rejected")
+	}
+	if _, ok := Validate(rfcSecret, code, now, lastStep); ok {
+		t.Errorf("the same code was accepted twice")
+	}
+	// 30 秒后同一验证码仍在时钟误差窗口内，但时间步已使用过
+	if _, ok := Validate(rfcSecret, code, now.Add(Period*time.Second), lastStep); ok {
+		t.Errorf("the same code was accepted again in the next step")
+	}
+	// 使用过后一个时间步的验证码后，前一个时间步的验证码也不再有效
+	previous, _ := CodeAt(rfcSecret, lastStep-1)
+	if _, ok := Validate(rfcSecret, previous, now, lastStep); ok {
+		t.Errorf("an older code was accepted after a newer one was used")
+	}
+	next, _ := CodeAt(rfcSecret, lastStep+1)
+	if step, ok := Validate(rfcSecret, next, now, lastStep); !ok || step != lastStep+1 {
+		t.Errorf("the next step code was rejected: %d, %v", step, ok)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"classOrder-backend/config"
 	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/notify"
 	"classOrder-backend/internal/router"
 	"log"
 	"net/http"
@@ -20,6 +21,9 @@ func main() {
 	// 初始化数据库连接
 	database.InitDB()
 
+	// 初始化通知渠道
+	notify.InitNotifier()
+
 	// 设置路由
 	r := router.SetupRouter()
 
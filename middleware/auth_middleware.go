@@ -2,6 +2,8 @@ package middleware
 
 import (
 	"classOrder-backend/config"
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
 	"net/http"
 	"strings"
 
@@ -41,9 +43,23 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
+			// 令牌所属的会话被撤销（退出登录、管理员撤销等）后拒绝访问
+			sessionID, _ := claims["sid"].(string)
+			if !sessionActive(sessionID) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+			// 需要修改密码的账号只能访问 AllowPendingPasswordChange 标记的接口
+			if pending, _ := claims["pwd_change"].(bool); pending && !c.GetBool("password_change_allowed") {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Password change required", "password_change_required": true})
+				c.Abort()
+				return
+			}
 			// 将用户信息存储到context中，方便后续handler使用
 			c.Set("user_id", claims["user_id"])
 			c.Set("role", claims["role"])
+			c.Set("session_id", sessionID)
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -53,23 +69,28 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AdminAuthMiddleware 是一个验证是否为管理员的中间件
-// 这个中间件应该在JWTAuthMiddleware之后使用
-func AdminAuthMiddleware() gin.HandlerFunc {
+// AllowPendingPasswordChange 标记允许尚未修改初始密码的用户访问的接口（修改密码、退出登录）
+// 这个中间件应该在JWTAuthMiddleware之前使用
+func AllowPendingPasswordChange() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Role not found in token."})
-			c.Abort()
-			return
-		}
-
-		if role.(string) != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Administrator role required."})
-			c.Abort()
-			return
-		}
-
+		c.Set("password_change_allowed", true)
 		c.Next()
 	}
+}
+
+// sessionActive 检查会话是否存在且未被撤销
+func sessionActive(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+	var count int64
+	database.DB.Model(&models.AuthSession{}).Where("id = ? AND revoked_at IS NULL", sessionID).Count(&count)
+	return count > 0
+}
+
+// AdminAuthMiddleware 是一个验证是否为管理员的中间件，等价于要求拥有全部权限
+// 新代码请使用 RequirePermission 指定具体权限
+// 这个中间件应该在JWTAuthMiddleware之后使用
+func AdminAuthMiddleware() gin.HandlerFunc {
+	return RequirePermission(models.PermissionAll)
 } 
\ No newline at end of file
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// permissionsOf 返回当前用户角色拥有的权限，每个请求只查询一次数据库；
+// 权限按角色实时读取，修改角色权限后无需重新登录即可生效
+func permissionsOf(c *gin.Context) (map[string]bool, error) {
+	if cached, exists := c.Get("permissions"); exists {
+		return cached.(map[string]bool), nil
+	}
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+	var perms []string
+	if err := database.DB.Model(&models.RolePermission{}).Where("role_name = ?", roleName).Pluck("permission", &perms).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(perms))
+	for _, p := range perms {
+		set[p] = true
+	}
+	c.Set("permissions", set)
+	return set, nil
+}
+
+// HasPermission 判断当前用户是否拥有某项权限，拥有 "*" 的角色视为拥有全部权限
+func HasPermission(c *gin.Context, perm string) bool {
+	perms, err := permissionsOf(c)
+	if err != nil {
+		return false
+	}
+	return perms[models.PermissionAll] || perms[perm]
+}
+
+// RequirePermission 要求当前用户的角色拥有指定权限
+// 这个中间件应该在JWTAuthMiddleware之后使用
+func RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("role"); !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Role not found in token."})
+			c.Abort()
+			return
+		}
+		perms, err := permissionsOf(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load permissions"})
+			c.Abort()
+			return
+		}
+		if !perms[models.PermissionAll] && !perms[perm] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Permission " + perm + " required."})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"classOrder-backend/internal/database"
+	"classOrder-backend/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CoachScopeMiddleware 对没有 allPermission 权限的用户查找其对应的教练记录，并将 coach_id 存入 context，
+// 后续 handler 据此把查询和修改限制在该教练自己的数据内；拥有 allPermission 的用户（管理员、前台等）不受限制
+// 这个中间件应该在JWTAuthMiddleware之后使用
+func CoachScopeMiddleware(allPermission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if HasPermission(c, allPermission) {
+			c.Set("coach_scope_all", true)
+			c.Next()
+			return
+		}
+		userID, ok := c.Get("user_id")
+		id, isFloat := userID.(float64) // JWT 默认 float64
+		if !ok || !isFloat {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in token"})
+			c.Abort()
+			return
+		}
+		var coach models.Coach
+		if err := database.DB.Select("id").Where("user_id = ?", uint(id)).First(&coach).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Coach profile not found."})
+			c.Abort()
+			return
+		}
+		c.Set("coach_id", coach.ID)
+		c.Next()
+	}
+}
+
+// CoachOwnershipMiddleware 校验路径参数 param 指定的记录属于当前教练，model 对应的表需要有 coach_id 字段
+// 这个中间件应该在CoachScopeMiddleware之后使用，不受教练范围限制的用户直接放行
+func CoachOwnershipMiddleware(model interface{}, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("coach_scope_all") {
+			c.Next()
+			return
+		}
+		coachID, exists := c.Get("coach_id")
+		if !exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. Coach scope not found."})
+			c.Abort()
+			return
+		}
+
+		var owner struct{ CoachID uint }
+		result := database.DB.Model(model).Select("coach_id").Where("id = ?", c.Param(param)).Limit(1).Scan(&owner)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check ownership"})
+			c.Abort()
+			return
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+			c.Abort()
+			return
+		}
+		if owner.CoachID != coachID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied. This record belongs to another coach."})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
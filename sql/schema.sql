@@ -3,10 +3,110 @@ CREATE TABLE IF NOT EXISTS users (
   id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
   username VARCHAR(255) NOT NULL UNIQUE,
   password_hash VARCHAR(255) NOT NULL,
-  role VARCHAR(50) NOT NULL, -- 'admin' 或 'coach'
+  role VARCHAR(50) NOT NULL, -- 角色名，见 roles 表
+  totp_secret VARCHAR(64), -- 两步验证密钥（base32）
+  totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  totp_last_step BIGINT NOT NULL DEFAULT 0, -- 最近一次使用的时间步，防止重放
+  disabled BOOLEAN NOT NULL DEFAULT FALSE, -- 停用的账号不能登录
+  must_change_password BOOLEAN NOT NULL DEFAULT FALSE, -- 下次登录后必须先修改密码
+  last_login_at DATETIME,
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
 
+-- 学员表，自助注册的学员关联登录账号，工作人员录入的学员可以没有账号
+CREATE TABLE IF NOT EXISTS students (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  user_id INT UNSIGNED UNIQUE,
+  name VARCHAR(100) NOT NULL,
+  phone VARCHAR(32),
+  email VARCHAR(255),
+  birth_date DATE,
+  discipline VARCHAR(20), -- ski/snowboard/both
+  level VARCHAR(50), -- 技术等级
+  guardian_name VARCHAR(100),
+  guardian_phone VARCHAR(32), -- 未成年学员的监护人联系方式
+  notes TEXT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_students_name (name),
+  INDEX idx_students_phone (phone),
+  INDEX idx_students_email (email),
+  INDEX idx_students_guardian_phone (guardian_phone),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- 角色表，内置角色由数据迁移写入
+CREATE TABLE IF NOT EXISTS roles (
+  name VARCHAR(50) PRIMARY KEY,
+  description VARCHAR(255),
+  built_in BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 角色权限表，权限格式为 资源:操作，"*" 表示全部权限
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_name VARCHAR(50) NOT NULL,
+  permission VARCHAR(50) NOT NULL,
+  PRIMARY KEY (role_name, permission),
+  FOREIGN KEY (role_name) REFERENCES roles(name) ON DELETE CASCADE
+);
+
+-- 两步验证恢复码表，只保存摘要
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  user_id INT UNSIGNED NOT NULL,
+  code_hash CHAR(64) NOT NULL,
+  used_at DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_recovery_codes_user_id (user_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 登录会话表
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id CHAR(32) PRIMARY KEY,
+  user_id INT UNSIGNED NOT NULL,
+  user_agent VARCHAR(255),
+  ip VARCHAR(64),
+  revoked_at DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_auth_sessions_user_id (user_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 刷新令牌表，只保存令牌摘要
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  session_id CHAR(32) NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME, -- 已换发新令牌的时间
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_refresh_tokens_session_id (session_id),
+  FOREIGN KEY (session_id) REFERENCES auth_sessions(id) ON DELETE CASCADE
+);
+
+-- 密码重置令牌表，只保存令牌摘要
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  user_id INT UNSIGNED NOT NULL,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  expires_at DATETIME NOT NULL,
+  used_at DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_password_reset_tokens_user_id (user_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- 登录失败记录表，用于退避和锁定
+CREATE TABLE IF NOT EXISTS login_throttles (
+  subject VARCHAR(255) PRIMARY KEY, -- user:<用户名> 或 ip:<地址>
+  failures INT NOT NULL DEFAULT 0,
+  blocked_until DATETIME,
+  locked BOOLEAN NOT NULL DEFAULT FALSE,
+  last_failed_at DATETIME
+);
+
 -- 教练表
 CREATE TABLE IF NOT EXISTS coaches (
   id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
@@ -14,25 +114,260 @@ CREATE TABLE IF NOT EXISTS coaches (
   name VARCHAR(255) NOT NULL,
   description TEXT,
   avatar_url VARCHAR(255),
+  discipline VARCHAR(20), -- ski/snowboard/both
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_coaches_discipline (discipline),
   FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+-- 教练资格证书表
+CREATE TABLE IF NOT EXISTS coach_certifications (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED NOT NULL,
+  body VARCHAR(50) NOT NULL, -- 颁证机构，例如 PSIA、CASI
+  level VARCHAR(50),
+  discipline VARCHAR(20),
+  expires_at DATE, -- 为空表示长期有效
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_coach_certifications_coach_id (coach_id),
+  INDEX idx_coach_certifications_body (body),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 教练专长标签表
+CREATE TABLE IF NOT EXISTS coach_specialties (
+  coach_id INT UNSIGNED NOT NULL,
+  tag VARCHAR(50) NOT NULL,
+  PRIMARY KEY (coach_id, tag),
+  INDEX idx_coach_specialties_tag (tag),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 教练授课语言表
+CREATE TABLE IF NOT EXISTS coach_languages (
+  coach_id INT UNSIGNED NOT NULL,
+  language VARCHAR(20) NOT NULL, -- 例如 zh、en
+  PRIMARY KEY (coach_id, language),
+  INDEX idx_coach_languages_language (language),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 日历订阅表，coach_id 为空表示全校订阅，令牌只保存摘要
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED UNIQUE,
+  token_hash CHAR(64) NOT NULL UNIQUE,
+  created_by INT UNSIGNED NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
 -- 预约表
 CREATE TABLE IF NOT EXISTS bookings (
   id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
   coach_id INT UNSIGNED NOT NULL,
   booking_date DATE NOT NULL,
-  time_slot VARCHAR(50) NOT NULL,
+  time_slot VARCHAR(255) NOT NULL, -- 由 booking_slots 生成的展示字符串
   client_info VARCHAR(255),
+  status VARCHAR(20) NOT NULL DEFAULT 'confirmed', -- pending/confirmed/completed/cancelled/no_show
+  status_reason VARCHAR(255),
+  status_changed_at DATETIME,
+  course_id INT UNSIGNED,
+  price INT, -- 预约时的课程价格快照
+  series_id INT UNSIGNED, -- 所属的重复预约系列
+  series_exception BOOLEAN NOT NULL DEFAULT FALSE, -- 单独修改过的系列预约
+  student_id INT UNSIGNED, -- 关联的学员
+  sequence INT NOT NULL DEFAULT 0, -- 修改次数，用作日历订阅中的 SEQUENCE
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_bookings_status (status),
+  INDEX idx_bookings_course_id (course_id),
+  INDEX idx_bookings_series_id (series_id),
+  INDEX idx_bookings_student_id (student_id),
+  INDEX idx_bookings_coach_date (coach_id, booking_date),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 教练日锁表，写入某教练某天的预约前先锁定对应行，防止并发重复预约
+CREATE TABLE IF NOT EXISTS coach_day_locks (
+  coach_id INT UNSIGNED NOT NULL,
+  date DATE NOT NULL,
+  PRIMARY KEY (coach_id, date)
+);
+
+-- 重复预约系列表
+CREATE TABLE IF NOT EXISTS booking_series (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED NOT NULL,
+  rrule VARCHAR(255) NOT NULL, -- 例如 FREQ=WEEKLY;BYDAY=SA;COUNT=10
+  start_date DATE NOT NULL,
+  student_name VARCHAR(255),
+  created_by INT UNSIGNED NOT NULL,
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_booking_series_coach_id (coach_id),
   FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
 );
 
+-- 预约状态变更历史表
+CREATE TABLE IF NOT EXISTS booking_status_changes (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  booking_id INT UNSIGNED NOT NULL,
+  from_status VARCHAR(20),
+  to_status VARCHAR(20) NOT NULL,
+  reason VARCHAR(255),
+  changed_by INT UNSIGNED NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_booking_status_changes_booking_id (booking_id),
+  FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
+-- 课程报告表，教练在课后为已完成的预约填写，每个预约一份
+CREATE TABLE IF NOT EXISTS lesson_reports (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  booking_id INT UNSIGNED NOT NULL UNIQUE,
+  coach_id INT UNSIGNED NOT NULL,
+  student_id INT UNSIGNED,
+  skills VARCHAR(500), -- 练习的技术要点，逗号分隔
+  level VARCHAR(50), -- 课后评定的技术等级
+  notes TEXT,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_lesson_reports_coach_id (coach_id),
+  INDEX idx_lesson_reports_student_id (student_id),
+  FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
+-- 课程报告照片表
+CREATE TABLE IF NOT EXISTS lesson_report_photos (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  report_id INT UNSIGNED NOT NULL,
+  url VARCHAR(255) NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_lesson_report_photos_report_id (report_id),
+  FOREIGN KEY (report_id) REFERENCES lesson_reports(id) ON DELETE CASCADE
+);
+
+-- 学员评价表，每个已完成的预约一条，审核通过后公开
+CREATE TABLE IF NOT EXISTS coach_reviews (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  booking_id INT UNSIGNED NOT NULL UNIQUE,
+  coach_id INT UNSIGNED NOT NULL,
+  student_id INT UNSIGNED NOT NULL,
+  rating TINYINT NOT NULL, -- 1-5
+  comment TEXT,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending/approved/hidden
+  moderated_by INT UNSIGNED,
+  moderated_at DATETIME,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_coach_reviews_coach_id (coach_id),
+  INDEX idx_coach_reviews_student_id (student_id),
+  INDEX idx_coach_reviews_status (status),
+  FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
+-- 预约时间段表
+CREATE TABLE IF NOT EXISTS booking_slots (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  booking_id INT UNSIGNED NOT NULL,
+  start_time CHAR(5) NOT NULL, -- HH:MM
+  end_time CHAR(5) NOT NULL, -- HH:MM
+  INDEX idx_booking_slots_booking_id (booking_id),
+  FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
 -- 课程表
 CREATE TABLE IF NOT EXISTS courses (
   id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
   name VARCHAR(255) NOT NULL,
   description TEXT,
-  price INT
-); 
\ No newline at end of file
+  price INT,
+  duration_minutes INT NOT NULL DEFAULT 0, -- 单次课时长，0 表示不限
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+); 
+-- 教练每周工作时段表
+CREATE TABLE IF NOT EXISTS coach_working_hours (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED NOT NULL,
+  weekday TINYINT NOT NULL, -- 0 表示周日
+  start_time CHAR(5) NOT NULL,
+  end_time CHAR(5) NOT NULL,
+  INDEX idx_coach_working_hours_coach_id (coach_id),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 教练日期覆盖表（某天单独设置的工作时段或休息）
+CREATE TABLE IF NOT EXISTS coach_date_overrides (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED NOT NULL,
+  date DATE NOT NULL,
+  start_time CHAR(5),
+  end_time CHAR(5),
+  closed BOOLEAN NOT NULL DEFAULT FALSE,
+  note VARCHAR(255),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_coach_override_date (coach_id, date),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 休假/停课表（coach_id 为空表示全校停课）
+CREATE TABLE IF NOT EXISTS blackouts (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED,
+  start_date DATE NOT NULL,
+  end_date DATE NOT NULL,
+  start_time CHAR(5), -- 为空表示整天
+  end_time CHAR(5),
+  reason VARCHAR(255),
+  created_by INT UNSIGNED NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_blackouts_coach_id (coach_id),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);
+
+-- 团体课表（通过一条预约占用教练时间）
+CREATE TABLE IF NOT EXISTS group_sessions (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  booking_id INT UNSIGNED NOT NULL UNIQUE,
+  title VARCHAR(255) NOT NULL,
+  capacity INT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE CASCADE
+);
+
+-- 团体课报名表
+CREATE TABLE IF NOT EXISTS group_enrollments (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  group_session_id INT UNSIGNED NOT NULL,
+  student_name VARCHAR(255) NOT NULL,
+  contact VARCHAR(100),
+  status VARCHAR(20) NOT NULL DEFAULT 'enrolled', -- enrolled/cancelled
+  price INT,
+  created_by INT UNSIGNED NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  cancelled_at DATETIME,
+  INDEX idx_group_enrollments_group_session_id (group_session_id),
+  FOREIGN KEY (group_session_id) REFERENCES group_sessions(id) ON DELETE CASCADE
+);
+
+-- 候补表
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+  id INT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  coach_id INT UNSIGNED NOT NULL,
+  date DATE NOT NULL,
+  time_slot VARCHAR(255) NOT NULL,
+  student_name VARCHAR(255) NOT NULL,
+  student_id INT UNSIGNED, -- 关联的学员
+  contact VARCHAR(100),
+  course_id INT UNSIGNED,
+  status VARCHAR(20) NOT NULL DEFAULT 'waiting', -- waiting/promoted/cancelled
+  promoted_booking_id INT UNSIGNED,
+  promoted_at DATETIME,
+  notified_at DATETIME,
+  created_by INT UNSIGNED NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_waitlist_coach_date (coach_id, date),
+  INDEX idx_waitlist_entries_status (status),
+  FOREIGN KEY (coach_id) REFERENCES coaches(id) ON DELETE CASCADE
+);